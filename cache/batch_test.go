@@ -0,0 +1,48 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/saigontechnology/go-shared-packages/cache"
+)
+
+func TestCache_BatchOperations(t *testing.T) {
+	t.Parallel()
+	for _, tc := range backendsForTest() {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			ctx := context.Background()
+			c := tc.newCache(t)
+			r.NoError(c.MSet(ctx, []cache.Entry{
+				{Key: "a", Data: "1", Expire: time.Minute},
+				{Key: "b", Data: 2, Expire: time.Minute},
+				{Key: "c", Data: true},
+			}, nil))
+
+			var a string
+			var b int
+			var c3 bool
+			var missing string
+			found, err := c.MGet(ctx, []string{"a", "b", "c", "missing"}, []interface{}{&a, &b, &c3, &missing}, nil)
+			r.NoError(err)
+			r.Equal([]bool{true, true, true, false}, found)
+			r.Equal("1", a)
+			r.Equal(2, b)
+			r.True(c3)
+
+			r.NoError(c.MDel(ctx, "a", "c", "missing"))
+			found, err = c.MGet(ctx, []string{"a", "c"}, []interface{}{&a, &c3}, nil)
+			r.NoError(err)
+			r.Equal([]bool{false, false}, found)
+
+			_, err = c.MGet(ctx, []string{"a"}, nil, nil)
+			r.Error(err)
+		})
+	}
+}
@@ -0,0 +1,394 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/saigontechnology/go-shared-packages/prometheus"
+)
+
+// ErrCircuitOpen is returned by HealthCheck, writes, invalidations and counters while Redis is considered down.
+var ErrCircuitOpen = errors.New("[Cache] circuit breaker is open")
+
+// The values are reported by the cache_breaker_state gauge.
+const (
+	breakerClosed   = 0
+	breakerHalfOpen = 1
+	breakerOpen     = 2
+)
+
+// breakerCache stops calling a Cache after consecutive connection failures, so callers do not wait for
+// dial timeouts while Redis is down. While the breaker is open, reads are misses, the same way as dumpCache,
+// and writes and invalidations fail with ErrCircuitOpen. After openTimeout, one call is let through to probe
+// whether Redis is back.
+type breakerCache struct {
+	cache       Cache
+	threshold   int
+	openTimeout time.Duration
+	metric      prometheus.CacheMetric
+	now         func() time.Time
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+}
+
+func newBreakerCache(cache Cache, threshold int, openTimeout time.Duration) *breakerCache {
+	b := &breakerCache{
+		cache:       cache,
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		metric:      prometheus.GetCacheMetric(),
+		now:         time.Now,
+	}
+	b.metric.SetCacheBreakerState(breakerClosed)
+	return b
+}
+
+// call runs fn unless the breaker is open, it reports whether fn was run.
+func (b *breakerCache) call(ctx context.Context, fn func() error) (bool, error) {
+	if !b.allow() {
+		return false, nil
+	}
+	err := fn()
+	b.record(ctx, err)
+	return true, err
+}
+
+// mutate runs fn like call, it returns ErrCircuitOpen when fn was not run. A write or an invalidation
+// which is skipped must not look successful, the caller would rely on a value Redis does not have.
+func (b *breakerCache) mutate(ctx context.Context, fn func() error) error {
+	called, err := b.call(ctx, fn)
+	if !called {
+		return ErrCircuitOpen
+	}
+	return err
+}
+
+func (b *breakerCache) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerClosed:
+		return true
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return false
+		}
+		// Only the probe is let through until it succeeds or fails
+		b.setState(breakerHalfOpen)
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *breakerCache) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil && ctx.Err() != nil {
+		// The call ran out of the time given by the caller, it tells nothing about Redis.
+		// An interrupted probe lets the next call probe again.
+		if b.state == breakerHalfOpen {
+			b.setState(breakerOpen)
+		}
+		return
+	}
+	if !isConnectionError(err) {
+		b.failures = 0
+		if b.state != breakerClosed {
+			log.Println("[Cache] circuit breaker is closed, Redis is reachable again")
+			b.setState(breakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		if b.state == breakerClosed {
+			log.Printf("[Cache] circuit breaker is open after %d consecutive failures. Error: %s", b.failures, err.Error())
+		}
+		b.openedAt = b.now()
+		b.setState(breakerOpen)
+	}
+}
+
+// setState must be called with mu held.
+func (b *breakerCache) setState(state int) {
+	b.state = state
+	b.metric.SetCacheBreakerState(state)
+}
+
+// isConnectionError tells whether err means Redis could not be reached: a failed dial, read or write,
+// a network timeout or a closed connection. Misses, server replies and errors of the caller,
+// like a cancelled context or a value which can't be scanned, do not trip the breaker.
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, redis.ErrClosed) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func (b *breakerCache) Get(ctx context.Context, key string, data interface{}, tag *string) error {
+	called, err := b.call(ctx, func() error {
+		return b.cache.Get(ctx, key, data, tag)
+	})
+	if !called {
+		b.metric.CountCacheMiss(tag)
+		return ErrCacheMiss
+	}
+	return err
+}
+
+func (b *breakerCache) Set(
+	ctx context.Context,
+	key string,
+	data interface{},
+	expire time.Duration,
+	tag *string,
+) error {
+	return b.mutate(ctx, func() error {
+		return b.cache.Set(ctx, key, data, expire, tag)
+	})
+}
+
+func (b *breakerCache) RemoveHashKey(ctx context.Context, key string) error {
+	return b.mutate(ctx, func() error {
+		return b.cache.RemoveHashKey(ctx, key)
+	})
+}
+
+func (b *breakerCache) HGet(ctx context.Context, key, field string, data interface{}) error {
+	called, err := b.call(ctx, func() error {
+		return b.cache.HGet(ctx, key, field, data)
+	})
+	if !called {
+		return ErrCacheMiss
+	}
+	return err
+}
+
+func (b *breakerCache) HSet(
+	ctx context.Context,
+	key, field string,
+	data interface{},
+	expire time.Duration,
+) error {
+	return b.mutate(ctx, func() error {
+		return b.cache.HSet(ctx, key, field, data, expire)
+	})
+}
+
+func (b *breakerCache) DelKeysWithPattern(ctx context.Context, pattern string) error {
+	return b.mutate(ctx, func() error {
+		return b.cache.DelKeysWithPattern(ctx, pattern)
+	})
+}
+
+func (b *breakerCache) UnlinkKeysWithPattern(ctx context.Context, pattern string) (int64, error) {
+	var deleted int64
+	err := b.mutate(ctx, func() error {
+		var err error
+		deleted, err = b.cache.UnlinkKeysWithPattern(ctx, pattern)
+		return err
+	})
+	return deleted, err
+}
+
+// UnlinkKeysWithPatternAsync only reports the number of deleted keys once the deletion is over.
+func (b *breakerCache) UnlinkKeysWithPatternAsync(ctx context.Context, pattern string) *Deletion {
+	return runDeletion(ctx, func(ctx context.Context, progress func(deleted int64)) error {
+		deleted, err := b.UnlinkKeysWithPattern(ctx, pattern)
+		progress(deleted)
+		return err
+	})
+}
+
+func (b *breakerCache) Del(ctx context.Context, key string) error {
+	return b.mutate(ctx, func() error {
+		return b.cache.Del(ctx, key)
+	})
+}
+
+func (b *breakerCache) SetWithTags(
+	ctx context.Context,
+	key string,
+	data interface{},
+	expire time.Duration,
+	tag *string,
+	invalidationTags ...string,
+) error {
+	return b.mutate(ctx, func() error {
+		return b.cache.SetWithTags(ctx, key, data, expire, tag, invalidationTags...)
+	})
+}
+
+func (b *breakerCache) InvalidateTags(ctx context.Context, invalidationTags ...string) error {
+	return b.mutate(ctx, func() error {
+		return b.cache.InvalidateTags(ctx, invalidationTags...)
+	})
+}
+
+func (b *breakerCache) MGet(
+	ctx context.Context,
+	keys []string,
+	data []interface{},
+	tag *string,
+) ([]bool, error) {
+	var found []bool
+	called, err := b.call(ctx, func() error {
+		var err error
+		found, err = b.cache.MGet(ctx, keys, data, tag)
+		return err
+	})
+	if !called {
+		for range keys {
+			b.metric.CountCacheMiss(tag)
+		}
+		return make([]bool, len(keys)), nil
+	}
+	return found, err
+}
+
+func (b *breakerCache) MSet(ctx context.Context, entries []Entry, tag *string) error {
+	return b.mutate(ctx, func() error {
+		return b.cache.MSet(ctx, entries, tag)
+	})
+}
+
+func (b *breakerCache) MDel(ctx context.Context, keys ...string) error {
+	return b.mutate(ctx, func() error {
+		return b.cache.MDel(ctx, keys...)
+	})
+}
+
+func (b *breakerCache) HGetAll(ctx context.Context, key string) (Hash, error) {
+	hash := Hash{}
+	_, err := b.call(ctx, func() error {
+		var err error
+		hash, err = b.cache.HGetAll(ctx, key)
+		return err
+	})
+	return hash, err
+}
+
+func (b *breakerCache) HMSet(
+	ctx context.Context,
+	key string,
+	values map[string]interface{},
+	expire time.Duration,
+) error {
+	return b.mutate(ctx, func() error {
+		return b.cache.HMSet(ctx, key, values, expire)
+	})
+}
+
+func (b *breakerCache) HDel(ctx context.Context, key string, fields ...string) error {
+	return b.mutate(ctx, func() error {
+		return b.cache.HDel(ctx, key, fields...)
+	})
+}
+
+func (b *breakerCache) Incr(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	return b.IncrBy(ctx, key, 1, expire)
+}
+
+// IncrBy returns ErrCircuitOpen while the breaker is open, a counter has no sensible value without Redis.
+func (b *breakerCache) IncrBy(ctx context.Context, key string, value int64, expire time.Duration) (int64, error) {
+	var counter int64
+	called, err := b.call(ctx, func() error {
+		var err error
+		counter, err = b.cache.IncrBy(ctx, key, value, expire)
+		return err
+	})
+	if !called {
+		return 0, ErrCircuitOpen
+	}
+	return counter, err
+}
+
+func (b *breakerCache) Decr(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	return b.IncrBy(ctx, key, -1, expire)
+}
+
+func (b *breakerCache) Expire(ctx context.Context, key string, expire time.Duration) error {
+	return b.mutate(ctx, func() error {
+		return b.cache.Expire(ctx, key, expire)
+	})
+}
+
+func (b *breakerCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var ttl time.Duration
+	called, err := b.call(ctx, func() error {
+		var err error
+		ttl, err = b.cache.TTL(ctx, key)
+		return err
+	})
+	if !called {
+		return 0, ErrCacheMiss
+	}
+	return ttl, err
+}
+
+func (b *breakerCache) ZAdd(ctx context.Context, key string, expire time.Duration, members ...ZMember) error {
+	return b.mutate(ctx, func() error {
+		return b.cache.ZAdd(ctx, key, expire, members...)
+	})
+}
+
+func (b *breakerCache) ZRangeByScore(ctx context.Context, key string, by ZRangeBy) ([]ZMember, error) {
+	var members []ZMember
+	_, err := b.call(ctx, func() error {
+		var err error
+		members, err = b.cache.ZRangeByScore(ctx, key, by)
+		return err
+	})
+	return members, err
+}
+
+func (b *breakerCache) ZRem(ctx context.Context, key string, members ...string) error {
+	return b.mutate(ctx, func() error {
+		return b.cache.ZRem(ctx, key, members...)
+	})
+}
+
+func (b *breakerCache) BumpVersion(ctx context.Context, group string) error {
+	return b.mutate(ctx, func() error {
+		return b.cache.BumpVersion(ctx, group)
+	})
+}
+
+// tryLock keeps the load lock of Typed working through the breaker.
+func (b *breakerCache) tryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	locker, ok := b.cache.(loadLocker)
+	if !ok {
+		return nil, false, errors.New("[Cache] cache does not support locks")
+	}
+	var unlock func()
+	var acquired bool
+	called, err := b.call(ctx, func() error {
+		var err error
+		unlock, acquired, err = locker.tryLock(ctx, key, ttl)
+		return err
+	})
+	if !called {
+		return nil, false, ErrCircuitOpen
+	}
+	return unlock, acquired, err
+}
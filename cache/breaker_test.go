@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakerCache(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	b := newBreakerCache(newMiniRedisForTest(t, mr), 2, time.Minute)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	r.NoError(b.Set(ctx, "key", "value", time.Minute, nil))
+	var value string
+	r.ErrorIs(b.Get(ctx, "missing", &value, nil), ErrCacheMiss)
+	r.Equal(breakerClosed, b.state)
+
+	mr.Close()
+	r.Error(b.Get(ctx, "key", &value, nil))
+	r.Error(b.Set(ctx, "key", "value", time.Minute, nil))
+	r.Equal(breakerOpen, b.state)
+
+	// Redis is not called while the breaker is open
+	r.ErrorIs(b.Get(ctx, "key", &value, nil), ErrCacheMiss)
+	r.ErrorIs(b.Set(ctx, "key", "value", time.Minute, nil), ErrCircuitOpen)
+	r.ErrorIs(b.Del(ctx, "key"), ErrCircuitOpen)
+	r.ErrorIs(b.InvalidateTags(ctx, "tag"), ErrCircuitOpen)
+	r.ErrorIs(b.BumpVersion(ctx, "user"), ErrCircuitOpen)
+	_, err := b.UnlinkKeysWithPattern(ctx, "*")
+	r.ErrorIs(err, ErrCircuitOpen)
+	found, err := b.MGet(ctx, []string{"key"}, []interface{}{&value}, nil)
+	r.NoError(err)
+	r.Equal([]bool{false}, found)
+
+	// A failed probe opens the breaker again
+	now = now.Add(time.Minute)
+	r.Error(b.Get(ctx, "key", &value, nil))
+	r.Equal(breakerOpen, b.state)
+
+	// go-redis retries dialing in the background, so the first probes may still fail
+	r.NoError(mr.Restart())
+	r.Eventually(func() bool {
+		now = now.Add(time.Minute)
+		return b.Get(ctx, "key", &value, nil) == nil
+	}, 5*time.Second, 100*time.Millisecond)
+	r.Equal("value", value)
+	r.Equal(breakerClosed, b.state)
+}
+
+type timeoutErrorForTest struct {
+	timeout bool
+}
+
+func (e timeoutErrorForTest) Error() string   { return "timeout error for test" }
+func (e timeoutErrorForTest) Timeout() bool   { return e.timeout }
+func (e timeoutErrorForTest) Temporary() bool { return false }
+
+func TestIsConnectionError(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		err      error
+		expected bool
+	}{
+		"no error":              {err: nil, expected: false},
+		"miss":                  {err: ErrCacheMiss, expected: false},
+		"server reply":          {err: redis.Nil, expected: false},
+		"cancelled context":     {err: context.Canceled, expected: false},
+		"context deadline":      {err: fmt.Errorf("get: %w", context.DeadlineExceeded), expected: false},
+		"error without timeout": {err: timeoutErrorForTest{timeout: false}, expected: false},
+		"network timeout":       {err: timeoutErrorForTest{timeout: true}, expected: true},
+		"dial error":            {err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, expected: true},
+		"closed connection":     {err: io.EOF, expected: true},
+		"closed client":         {err: redis.ErrClosed, expected: true},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, isConnectionError(tc.err))
+		})
+	}
+}
+
+func TestBreakerCache_CallerDeadline(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	b := newBreakerCache(newMiniRedisForTest(t, miniredis.RunT(t)), 1, time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	var value string
+	r.Error(b.Get(ctx, "key", &value, nil))
+	// A read timing out because of the deadline of the caller is not a failure of Redis either
+	b.record(ctx, &net.OpError{Op: "read", Err: timeoutErrorForTest{timeout: true}})
+	r.Equal(breakerClosed, b.state)
+}
@@ -0,0 +1,43 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/saigontechnology/go-shared-packages/cache"
+)
+
+type backendForTest struct {
+	name     string
+	newCache func(t *testing.T) cache.Cache
+}
+
+// backendsForTest returns the Cache implementations which must behave the same way.
+func backendsForTest() []backendForTest {
+	return []backendForTest{
+		{
+			name: "Redis",
+			newCache: func(t *testing.T) cache.Cache {
+				t.Helper()
+				return cache.NewMiniRedisForTest(t)
+			},
+		},
+		{
+			name: "Memory",
+			newCache: func(t *testing.T) cache.Cache {
+				t.Helper()
+				c, err := cache.NewMemoryCache(0, cache.EvictionPolicyLRU)
+				require.NoError(t, err)
+				return c
+			},
+		},
+		{
+			name: "Fake",
+			newCache: func(t *testing.T) cache.Cache {
+				t.Helper()
+				return cache.NewFakeCache()
+			},
+		},
+	}
+}
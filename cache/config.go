@@ -1,20 +1,68 @@
 package cache
 
 import (
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
+
+	"github.com/saigontechnology/go-shared-packages/env"
+)
+
+const (
+	ModeSingle   = "single"
+	ModeSentinel = "sentinel"
+	ModeCluster  = "cluster"
 )
 
-type config struct {
+type Config struct {
+	// Mode is one of single, sentinel or cluster
+	Mode string `default:"single" envconfig:"CACHE_MODE"`
+	// Addresses of sentinels or cluster nodes, Host is used when it is empty
+	Addresses        []string `default:""       envconfig:"CACHE_ADDRESSES"`
+	MasterName       string   `default:""       envconfig:"CACHE_MASTER_NAME"`
+	SentinelPassword string   `default:""       envconfig:"CACHE_SENTINEL_PASSWORD"`
+	// ReadFromReplica sends read-only commands to replicas in sentinel and cluster modes
+	ReadFromReplica bool `default:"false" envconfig:"CACHE_READ_FROM_REPLICA"`
+
+	// Host is the address of a single server, Database can not be selected in cluster mode or with ReadFromReplica
 	Host       string `default:"your-service-redis:6379" envconfig:"CACHE_HOST"`
 	Database   int    `default:"0"                       envconfig:"CACHE_DATABASE"`
 	Password   string `default:""                        envconfig:"CACHE_PASSWORD"`
 	Namespace  string `default:"your_service"            envconfig:"CACHE_NAMESPACE"`
 	TLSEnabled bool   `default:"false"                   envconfig:"CACHE_TLS_ENABLED"`
 	ScanCount  int    `default:"5000"                    envconfig:"CACHE_SCAN_COUNT"`
+	// MemoryMaxEntries is the maximum number of keys kept by the in-process cache, 0 means unlimited
+	MemoryMaxEntries     int    `default:"10000" envconfig:"CACHE_MEMORY_MAX_ENTRIES"`
+	MemoryEvictionPolicy string `default:"lru"   envconfig:"CACHE_MEMORY_EVICTION_POLICY"`
+	// NearTTL bounds how long the local tier of the two-tier cache keeps a value,
+	// it limits staleness when an invalidation message is lost
+	NearTTL time.Duration `default:"30s" envconfig:"CACHE_NEAR_TTL"`
+	// Codec marshals values which are not strings, bytes or numbers, it is one of json, msgpack, protobuf or gob
+	Codec string `default:"json" envconfig:"CACHE_CODEC"`
+	// Compression is one of none, snappy or zstd, values smaller than CompressionThreshold bytes are not compressed
+	Compression          string `default:"none" envconfig:"CACHE_COMPRESSION"`
+	CompressionThreshold int    `default:"1024" envconfig:"CACHE_COMPRESSION_THRESHOLD"`
+	// The circuit breaker of RedisCache opens after BreakerThreshold consecutive connection failures,
+	// Redis is not called until BreakerOpenTimeout has passed, then one call probes whether it is back.
+	// It is opt-in because reads become misses and writes fail with ErrCircuitOpen while it is open.
+	// TieredCache and Locker do not go through it
+	BreakerEnabled     bool          `default:"false" envconfig:"CACHE_BREAKER_ENABLED"`
+	BreakerThreshold   int           `default:"5"     envconfig:"CACHE_BREAKER_THRESHOLD"`
+	BreakerOpenTimeout time.Duration `default:"10s"   envconfig:"CACHE_BREAKER_OPEN_TIMEOUT"`
+	// VersionedGroups are the key prefixes before the first ':' whose keys are invalidated all at once by
+	// BumpVersion, the version of a group is cached in process for VersionLocalTTL
+	VersionedGroups []string      `default:""   envconfig:"CACHE_VERSIONED_GROUPS"`
+	VersionLocalTTL time.Duration `default:"5s" envconfig:"CACHE_VERSION_LOCAL_TTL"`
+	// StatsPingTimeout bounds the ping of the cache_up metric. Keys of StatsNamespaces, or of Namespace when it
+	// is empty, are counted with SCAN at most once per StatsKeysInterval. SCAN walks the whole keyspace,
+	// so the cache_keys metric is opt-in, 0 disables it
+	StatsPingTimeout  time.Duration `default:"1s" envconfig:"CACHE_STATS_PING_TIMEOUT"`
+	StatsNamespaces   []string      `default:""   envconfig:"CACHE_STATS_NAMESPACES"`
+	StatsKeysInterval time.Duration `default:"0"  envconfig:"CACHE_STATS_KEYS_INTERVAL"`
 }
 
-func newConfig() (*config, error) {
-	cfg := &config{}
+func NewConfig() (*Config, error) {
+	cfg := &Config{}
 	err := envconfig.Process("", cfg)
 	if err != nil {
 		return nil, err
@@ -22,3 +70,81 @@ func newConfig() (*config, error) {
 
 	return cfg, nil
 }
+
+// Option changes the configuration of a Provider created by New.
+type Option func(o *options)
+
+type options struct {
+	// cfg is given by WithConfig, the environment is not read when it is set
+	cfg     *Config
+	changes []func(cfg *Config)
+}
+
+// newConfigFromOptions applies the changes of the other options on top of the configuration
+// given by WithConfig and completed with defaults, or read from the environment without WithConfig.
+func newConfigFromOptions(opts ...Option) (*Config, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	cfg := o.cfg
+	if cfg == nil {
+		var err error
+		cfg, err = NewConfig()
+		if err != nil {
+			return nil, err
+		}
+	} else if err := env.SetDefaults(cfg); err != nil {
+		return nil, err
+	}
+	for _, change := range o.changes {
+		change(cfg)
+	}
+	return cfg, nil
+}
+
+func withChange(change func(cfg *Config)) Option {
+	return func(o *options) {
+		o.changes = append(o.changes, change)
+	}
+}
+
+// WithConfig replaces the configuration read from the environment, which is not read at all.
+// Fields left at their zero value get the default of their environment variable.
+func WithConfig(c Config) Option {
+	return func(o *options) {
+		o.cfg = &c
+	}
+}
+
+// WithHost connects to a single Redis server.
+func WithHost(host string) Option {
+	return withChange(func(cfg *Config) {
+		cfg.Mode = ModeSingle
+		cfg.Host = host
+	})
+}
+
+// WithNamespace prefixes keys with namespace, providers with different namespaces do not see each other's keys.
+func WithNamespace(namespace string) Option {
+	return withChange(func(cfg *Config) {
+		cfg.Namespace = namespace
+	})
+}
+
+// WithCodec sets the codec and the compression of values, see CACHE_CODEC and CACHE_COMPRESSION.
+func WithCodec(codec, compression string) Option {
+	return withChange(func(cfg *Config) {
+		cfg.Codec = codec
+		cfg.Compression = compression
+	})
+}
+
+// WithBreaker enables the circuit breaker of RedisCache, it is disabled when threshold is 0.
+func WithBreaker(threshold int, openTimeout time.Duration) Option {
+	return withChange(func(cfg *Config) {
+		cfg.BreakerEnabled = threshold > 0
+		cfg.BreakerThreshold = threshold
+		cfg.BreakerOpenTimeout = openTimeout
+	})
+}
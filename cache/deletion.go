@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Deletion is the handle of keys being deleted in the background, it is cancelled through the context
+// given to UnlinkKeysWithPatternAsync.
+type Deletion struct {
+	done    chan struct{}
+	deleted atomic.Int64
+	err     error
+}
+
+func runDeletion(ctx context.Context, fn func(ctx context.Context, progress func(deleted int64)) error) *Deletion {
+	d := &Deletion{done: make(chan struct{})}
+	go func() {
+		defer close(d.done)
+		d.err = fn(ctx, func(deleted int64) {
+			d.deleted.Add(deleted)
+		})
+	}()
+	return d
+}
+
+// Done is closed when the deletion is over.
+func (d *Deletion) Done() <-chan struct{} {
+	return d.done
+}
+
+// Deleted returns the number of keys deleted so far.
+func (d *Deletion) Deleted() int64 {
+	return d.deleted.Load()
+}
+
+// Wait returns the number of deleted keys and the errors of the deletion once it is over.
+func (d *Deletion) Wait() (int64, error) {
+	<-d.done
+	return d.deleted.Load(), d.err
+}
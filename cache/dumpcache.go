@@ -58,3 +58,117 @@ func (d *dumpCache) DelKeysWithPattern(ctx context.Context, pattern string) erro
 	log.Println("[DumpCache.DelKeyWithPattern] nothing to do")
 	return nil
 }
+
+func (d *dumpCache) UnlinkKeysWithPattern(ctx context.Context, pattern string) (int64, error) {
+	log.Println("[DumpCache.UnlinkKeysWithPattern] nothing to do")
+	return 0, nil
+}
+
+func (d *dumpCache) UnlinkKeysWithPatternAsync(ctx context.Context, pattern string) *Deletion {
+	log.Println("[DumpCache.UnlinkKeysWithPatternAsync] nothing to do")
+	return runDeletion(ctx, func(ctx context.Context, progress func(deleted int64)) error {
+		return nil
+	})
+}
+
+func (d *dumpCache) SetWithTags(
+	ctx context.Context,
+	key string,
+	data interface{},
+	expire time.Duration,
+	tag *string,
+	invalidationTags ...string,
+) error {
+	log.Println("[DumpCache.SetWithTags] nothing to do")
+	return nil
+}
+
+func (d *dumpCache) InvalidateTags(ctx context.Context, invalidationTags ...string) error {
+	log.Println("[DumpCache.InvalidateTags] nothing to do")
+	return nil
+}
+
+func (d *dumpCache) MGet(
+	ctx context.Context,
+	keys []string,
+	data []interface{},
+	tag *string,
+) ([]bool, error) {
+	log.Println("[DumpCache.MGet] nothing to do")
+	return make([]bool, len(keys)), nil
+}
+
+func (d *dumpCache) MSet(ctx context.Context, entries []Entry, tag *string) error {
+	log.Println("[DumpCache.MSet] nothing to do")
+	return nil
+}
+
+func (d *dumpCache) MDel(ctx context.Context, keys ...string) error {
+	log.Println("[DumpCache.MDel] nothing to do")
+	return nil
+}
+
+func (d *dumpCache) HGetAll(ctx context.Context, key string) (Hash, error) {
+	log.Println("[DumpCache.HGetAll] nothing to do")
+	return Hash{}, nil
+}
+
+func (d *dumpCache) HMSet(
+	ctx context.Context,
+	key string,
+	values map[string]interface{},
+	expire time.Duration,
+) error {
+	log.Println("[DumpCache.HMSet] nothing to do")
+	return nil
+}
+
+func (d *dumpCache) HDel(ctx context.Context, key string, fields ...string) error {
+	log.Println("[DumpCache.HDel] nothing to do")
+	return nil
+}
+
+func (d *dumpCache) Incr(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	log.Println("[DumpCache.Incr] nothing to do")
+	return 0, nil
+}
+
+func (d *dumpCache) IncrBy(ctx context.Context, key string, value int64, expire time.Duration) (int64, error) {
+	log.Println("[DumpCache.IncrBy] nothing to do")
+	return 0, nil
+}
+
+func (d *dumpCache) Decr(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	log.Println("[DumpCache.Decr] nothing to do")
+	return 0, nil
+}
+
+func (d *dumpCache) Expire(ctx context.Context, key string, expire time.Duration) error {
+	log.Println("[DumpCache.Expire] nothing to do")
+	return nil
+}
+
+func (d *dumpCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	log.Println("[DumpCache.TTL] nothing to do")
+	return 0, nil
+}
+
+func (d *dumpCache) ZAdd(ctx context.Context, key string, expire time.Duration, members ...ZMember) error {
+	log.Println("[DumpCache.ZAdd] nothing to do")
+	return nil
+}
+
+func (d *dumpCache) ZRangeByScore(ctx context.Context, key string, by ZRangeBy) ([]ZMember, error) {
+	log.Println("[DumpCache.ZRangeByScore] nothing to do")
+	return nil, nil
+}
+
+func (d *dumpCache) ZRem(ctx context.Context, key string, members ...string) error {
+	log.Println("[DumpCache.ZRem] nothing to do")
+	return nil
+}
+
+func (d *dumpCache) BumpVersion(ctx context.Context, group string) error {
+	log.Println("[DumpCache.BumpVersion] nothing to do")
+	return nil
+}
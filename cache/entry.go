@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// ErrKnownAbsent is returned by Typed when a key is cached as absent, the source of truth does not have it either.
+// Loaders return it to cache the absence of a value, see WithNegativeCaching.
+var ErrKnownAbsent = errors.New("[Cache] key is known to be absent")
+
+var errInvalidEntry = errors.New("[Cache] invalid entry")
+
+const (
+	entryFlagAbsent byte = 1 << iota
+
+	// entryHeaderLength is one byte of flags followed by the soft expiration in unix nanoseconds
+	entryHeaderLength = 9
+)
+
+// entry is what Typed stores when stale-while-revalidate or negative caching is enabled.
+// The hard TTL is the expiration of the key itself, the soft expiration is kept in the entry.
+type entry struct {
+	value        []byte
+	absent       bool
+	softExpireAt time.Time
+}
+
+func (e *entry) stale(now time.Time) bool {
+	return !e.softExpireAt.IsZero() && now.After(e.softExpireAt)
+}
+
+func (e *entry) encode() []byte {
+	raw := make([]byte, entryHeaderLength, entryHeaderLength+len(e.value))
+	if e.absent {
+		raw[0] |= entryFlagAbsent
+	}
+	if !e.softExpireAt.IsZero() {
+		binary.BigEndian.PutUint64(raw[1:entryHeaderLength], uint64(e.softExpireAt.UnixNano()))
+	}
+	return append(raw, e.value...)
+}
+
+func decodeEntry(raw []byte) (*entry, error) {
+	if len(raw) < entryHeaderLength {
+		return nil, errInvalidEntry
+	}
+	e := &entry{
+		value:  raw[entryHeaderLength:],
+		absent: raw[0]&entryFlagAbsent != 0,
+	}
+	if softExpireAt := binary.BigEndian.Uint64(raw[1:entryHeaderLength]); softExpireAt != 0 {
+		e.softExpireAt = time.Unix(0, int64(softExpireAt))
+	}
+	return e, nil
+}
@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+	"fmt"
+)
+
+type EvictionPolicy string
+
+const (
+	// EvictionPolicyLRU evicts the least recently used key.
+	EvictionPolicyLRU EvictionPolicy = "lru"
+	// EvictionPolicyLFU evicts the least frequently used key, the oldest one when keys have the same frequency.
+	EvictionPolicyLFU EvictionPolicy = "lfu"
+)
+
+// evictor keeps track of key usage to choose which key is evicted when a memory cache is full.
+type evictor interface {
+	add(key string)
+	touch(key string)
+	remove(key string)
+	victim() (string, bool)
+}
+
+func newEvictor(policy EvictionPolicy) (evictor, error) {
+	switch policy {
+	case EvictionPolicyLRU:
+		return newLRUEvictor(), nil
+	case EvictionPolicyLFU:
+		return newLFUEvictor(), nil
+	default:
+		return nil, fmt.Errorf("[Cache] eviction policy %s is not supported", policy)
+	}
+}
+
+type lruEvictor struct {
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+func newLRUEvictor() *lruEvictor {
+	return &lruEvictor{
+		order:    list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+func (e *lruEvictor) add(key string) {
+	if element, ok := e.elements[key]; ok {
+		e.order.MoveToFront(element)
+		return
+	}
+	e.elements[key] = e.order.PushFront(key)
+}
+
+func (e *lruEvictor) touch(key string) {
+	if element, ok := e.elements[key]; ok {
+		e.order.MoveToFront(element)
+	}
+}
+
+func (e *lruEvictor) remove(key string) {
+	if element, ok := e.elements[key]; ok {
+		e.order.Remove(element)
+		delete(e.elements, key)
+	}
+}
+
+func (e *lruEvictor) victim() (string, bool) {
+	element := e.order.Back()
+	if element == nil {
+		return "", false
+	}
+	return element.Value.(string), true
+}
+
+type lfuItem struct {
+	key   string
+	freq  uint64
+	seq   uint64
+	index int
+}
+
+// lfuHeap is a min-heap ordered by frequency, then by the last access.
+type lfuHeap []*lfuItem
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq == h[j].freq {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].freq < h[j].freq
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	item := x.(*lfuItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+type lfuEvictor struct {
+	items map[string]*lfuItem
+	heap  lfuHeap
+	seq   uint64
+}
+
+func newLFUEvictor() *lfuEvictor {
+	return &lfuEvictor{
+		items: make(map[string]*lfuItem),
+	}
+}
+
+func (e *lfuEvictor) add(key string) {
+	if _, ok := e.items[key]; ok {
+		e.touch(key)
+		return
+	}
+	e.seq++
+	item := &lfuItem{key: key, freq: 1, seq: e.seq}
+	e.items[key] = item
+	heap.Push(&e.heap, item)
+}
+
+func (e *lfuEvictor) touch(key string) {
+	item, ok := e.items[key]
+	if !ok {
+		return
+	}
+	e.seq++
+	item.freq++
+	item.seq = e.seq
+	heap.Fix(&e.heap, item.index)
+}
+
+func (e *lfuEvictor) remove(key string) {
+	item, ok := e.items[key]
+	if !ok {
+		return
+	}
+	heap.Remove(&e.heap, item.index)
+	delete(e.items, key)
+}
+
+func (e *lfuEvictor) victim() (string, bool) {
+	if len(e.heap) == 0 {
+		return "", false
+	}
+	return e.heap[0].key, true
+}
@@ -0,0 +1,388 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Call is a call of a Cache method recorded by FakeCache.
+type Call struct {
+	Method string
+	// Keys are the keys of the call, or its pattern, invalidation tags or group
+	Keys []string
+	// Args are the other arguments of the call, without the context
+	Args []interface{}
+	Err  error
+}
+
+type fault struct {
+	method string
+	key    string
+	err    error
+	delay  time.Duration
+}
+
+func (f *fault) matches(method string, keys []string) bool {
+	if f.method != "" && f.method != method {
+		return false
+	}
+	if f.key == "" {
+		return true
+	}
+	for _, key := range keys {
+		if key == f.key {
+			return true
+		}
+	}
+	return false
+}
+
+// FakeCache is a Cache for unit tests. Values are really stored in process, every call is recorded
+// and errors or latency can be injected per method and key.
+type FakeCache struct {
+	cache *memoryCache
+
+	mu     sync.Mutex
+	calls  []Call
+	faults []fault
+}
+
+// NewFakeCache creates an empty FakeCache.
+func NewFakeCache() *FakeCache {
+	c, _ := NewMemoryCache(0, EvictionPolicyLRU)
+	return &FakeCache{cache: c.(*memoryCache)}
+}
+
+// FailOn makes the calls of method with key fail with err, the call does nothing.
+// An empty method matches all methods and an empty key matches all keys.
+func (f *FakeCache) FailOn(method, key string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = append(f.faults, fault{method: method, key: key, err: err})
+}
+
+// DelayOn makes the calls of method with key wait for delay before they run, a call whose context is done
+// while it waits fails with the error of the context. Empty method and key match all, as with FailOn.
+func (f *FakeCache) DelayOn(method, key string, delay time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = append(f.faults, fault{method: method, key: key, delay: delay})
+}
+
+// Reset removes the recorded calls and the injected faults, stored values are kept.
+func (f *FakeCache) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+	f.faults = nil
+}
+
+// Calls returns the recorded calls of method in order, all calls when method is empty.
+func (f *FakeCache) Calls(method string) []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []Call
+	for _, call := range f.calls {
+		if method == "" || call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// CallsWithKey returns the recorded calls of method with key, all calls with key when method is empty.
+func (f *FakeCache) CallsWithKey(method, key string) []Call {
+	var calls []Call
+	for _, call := range f.Calls(method) {
+		for _, k := range call.Keys {
+			if k == key {
+				calls = append(calls, call)
+				break
+			}
+		}
+	}
+	return calls
+}
+
+// AssertCalled asserts that method was called with key.
+func (f *FakeCache) AssertCalled(t *testing.T, method, key string) bool {
+	t.Helper()
+	if len(f.CallsWithKey(method, key)) == 0 {
+		return assert.Fail(t, "[Cache] expected call was not made", "%s(%s) was not called", method, key)
+	}
+	return true
+}
+
+// AssertNotCalled asserts that method was not called with key.
+func (f *FakeCache) AssertNotCalled(t *testing.T, method, key string) bool {
+	t.Helper()
+	if calls := f.CallsWithKey(method, key); len(calls) > 0 {
+		return assert.Fail(t, "[Cache] unexpected call was made", "%s(%s) was called %d times", method, key, len(calls))
+	}
+	return true
+}
+
+// AssertSet asserts that key was set with the expire TTL by Set, SetWithTags or MSet.
+func (f *FakeCache) AssertSet(t *testing.T, key string, expire time.Duration) bool {
+	t.Helper()
+	var expires []time.Duration
+	for _, call := range f.CallsWithKey("", key) {
+		switch call.Method {
+		case "Set", "SetWithTags":
+			expires = append(expires, call.Args[1].(time.Duration))
+		case "MSet":
+			for _, entry := range call.Args[0].([]Entry) {
+				if entry.Key == key {
+					expires = append(expires, entry.Expire)
+				}
+			}
+		}
+	}
+	if len(expires) == 0 {
+		return assert.Fail(t, "[Cache] key was not set", "%s was not set", key)
+	}
+	return assert.Contains(t, expires, expire, "[Cache] %s was not set with TTL %s", key, expire)
+}
+
+// do runs fn unless an error is injected for the call, the call is recorded either way.
+func (f *FakeCache) do(ctx context.Context, method string, keys []string, args []interface{}, fn func() error) error {
+	f.mu.Lock()
+	var delay time.Duration
+	var err error
+	for i := range f.faults {
+		if !f.faults[i].matches(method, keys) {
+			continue
+		}
+		delay += f.faults[i].delay
+		if err == nil {
+			err = f.faults[i].err
+		}
+	}
+	f.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			if err == nil {
+				err = ctx.Err()
+			}
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+	if err == nil {
+		err = fn()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, Call{Method: method, Keys: keys, Args: args, Err: err})
+	return err
+}
+
+func (f *FakeCache) Get(ctx context.Context, key string, data interface{}, tag *string) error {
+	return f.do(ctx, "Get", []string{key}, []interface{}{data, tag}, func() error {
+		return f.cache.Get(ctx, key, data, tag)
+	})
+}
+
+func (f *FakeCache) Set(ctx context.Context, key string, data interface{}, expire time.Duration, tag *string) error {
+	return f.do(ctx, "Set", []string{key}, []interface{}{data, expire, tag}, func() error {
+		return f.cache.Set(ctx, key, data, expire, tag)
+	})
+}
+
+func (f *FakeCache) RemoveHashKey(ctx context.Context, key string) error {
+	return f.do(ctx, "RemoveHashKey", []string{key}, nil, func() error {
+		return f.cache.RemoveHashKey(ctx, key)
+	})
+}
+
+func (f *FakeCache) HGet(ctx context.Context, key, field string, data interface{}) error {
+	return f.do(ctx, "HGet", []string{key}, []interface{}{field, data}, func() error {
+		return f.cache.HGet(ctx, key, field, data)
+	})
+}
+
+func (f *FakeCache) HSet(ctx context.Context, key, field string, data interface{}, expire time.Duration) error {
+	return f.do(ctx, "HSet", []string{key}, []interface{}{field, data, expire}, func() error {
+		return f.cache.HSet(ctx, key, field, data, expire)
+	})
+}
+
+func (f *FakeCache) DelKeysWithPattern(ctx context.Context, pattern string) error {
+	return f.do(ctx, "DelKeysWithPattern", []string{pattern}, nil, func() error {
+		return f.cache.DelKeysWithPattern(ctx, pattern)
+	})
+}
+
+func (f *FakeCache) UnlinkKeysWithPattern(ctx context.Context, pattern string) (int64, error) {
+	var deleted int64
+	err := f.do(ctx, "UnlinkKeysWithPattern", []string{pattern}, nil, func() error {
+		var err error
+		deleted, err = f.cache.UnlinkKeysWithPattern(ctx, pattern)
+		return err
+	})
+	return deleted, err
+}
+
+func (f *FakeCache) UnlinkKeysWithPatternAsync(ctx context.Context, pattern string) *Deletion {
+	return runDeletion(ctx, func(ctx context.Context, progress func(deleted int64)) error {
+		return f.do(ctx, "UnlinkKeysWithPatternAsync", []string{pattern}, nil, func() error {
+			deleted, err := f.cache.UnlinkKeysWithPattern(ctx, pattern)
+			progress(deleted)
+			return err
+		})
+	})
+}
+
+func (f *FakeCache) Del(ctx context.Context, key string) error {
+	return f.do(ctx, "Del", []string{key}, nil, func() error {
+		return f.cache.Del(ctx, key)
+	})
+}
+
+func (f *FakeCache) SetWithTags(
+	ctx context.Context,
+	key string,
+	data interface{},
+	expire time.Duration,
+	tag *string,
+	invalidationTags ...string,
+) error {
+	return f.do(ctx, "SetWithTags", []string{key}, []interface{}{data, expire, tag, invalidationTags}, func() error {
+		return f.cache.SetWithTags(ctx, key, data, expire, tag, invalidationTags...)
+	})
+}
+
+func (f *FakeCache) InvalidateTags(ctx context.Context, invalidationTags ...string) error {
+	return f.do(ctx, "InvalidateTags", invalidationTags, nil, func() error {
+		return f.cache.InvalidateTags(ctx, invalidationTags...)
+	})
+}
+
+func (f *FakeCache) MGet(ctx context.Context, keys []string, data []interface{}, tag *string) ([]bool, error) {
+	var found []bool
+	err := f.do(ctx, "MGet", keys, []interface{}{data, tag}, func() error {
+		var err error
+		found, err = f.cache.MGet(ctx, keys, data, tag)
+		return err
+	})
+	return found, err
+}
+
+func (f *FakeCache) MSet(ctx context.Context, entries []Entry, tag *string) error {
+	keys := make([]string, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.Key
+	}
+	return f.do(ctx, "MSet", keys, []interface{}{entries, tag}, func() error {
+		return f.cache.MSet(ctx, entries, tag)
+	})
+}
+
+func (f *FakeCache) MDel(ctx context.Context, keys ...string) error {
+	return f.do(ctx, "MDel", keys, nil, func() error {
+		return f.cache.MDel(ctx, keys...)
+	})
+}
+
+func (f *FakeCache) HGetAll(ctx context.Context, key string) (Hash, error) {
+	hash := Hash{}
+	err := f.do(ctx, "HGetAll", []string{key}, nil, func() error {
+		var err error
+		hash, err = f.cache.HGetAll(ctx, key)
+		return err
+	})
+	return hash, err
+}
+
+func (f *FakeCache) HMSet(ctx context.Context, key string, values map[string]interface{}, expire time.Duration) error {
+	return f.do(ctx, "HMSet", []string{key}, []interface{}{values, expire}, func() error {
+		return f.cache.HMSet(ctx, key, values, expire)
+	})
+}
+
+func (f *FakeCache) HDel(ctx context.Context, key string, fields ...string) error {
+	return f.do(ctx, "HDel", []string{key}, []interface{}{fields}, func() error {
+		return f.cache.HDel(ctx, key, fields...)
+	})
+}
+
+func (f *FakeCache) Incr(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	return f.incrBy(ctx, "Incr", key, 1, expire)
+}
+
+func (f *FakeCache) IncrBy(ctx context.Context, key string, value int64, expire time.Duration) (int64, error) {
+	return f.incrBy(ctx, "IncrBy", key, value, expire)
+}
+
+func (f *FakeCache) Decr(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	return f.incrBy(ctx, "Decr", key, -1, expire)
+}
+
+func (f *FakeCache) incrBy(
+	ctx context.Context,
+	method, key string,
+	value int64,
+	expire time.Duration,
+) (int64, error) {
+	var counter int64
+	err := f.do(ctx, method, []string{key}, []interface{}{value, expire}, func() error {
+		var err error
+		counter, err = f.cache.IncrBy(ctx, key, value, expire)
+		return err
+	})
+	return counter, err
+}
+
+func (f *FakeCache) Expire(ctx context.Context, key string, expire time.Duration) error {
+	return f.do(ctx, "Expire", []string{key}, []interface{}{expire}, func() error {
+		return f.cache.Expire(ctx, key, expire)
+	})
+}
+
+func (f *FakeCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var ttl time.Duration
+	err := f.do(ctx, "TTL", []string{key}, nil, func() error {
+		var err error
+		ttl, err = f.cache.TTL(ctx, key)
+		return err
+	})
+	return ttl, err
+}
+
+func (f *FakeCache) ZAdd(ctx context.Context, key string, expire time.Duration, members ...ZMember) error {
+	return f.do(ctx, "ZAdd", []string{key}, []interface{}{expire, members}, func() error {
+		return f.cache.ZAdd(ctx, key, expire, members...)
+	})
+}
+
+func (f *FakeCache) ZRangeByScore(ctx context.Context, key string, by ZRangeBy) ([]ZMember, error) {
+	var members []ZMember
+	err := f.do(ctx, "ZRangeByScore", []string{key}, []interface{}{by}, func() error {
+		var err error
+		members, err = f.cache.ZRangeByScore(ctx, key, by)
+		return err
+	})
+	return members, err
+}
+
+func (f *FakeCache) ZRem(ctx context.Context, key string, members ...string) error {
+	return f.do(ctx, "ZRem", []string{key}, []interface{}{members}, func() error {
+		return f.cache.ZRem(ctx, key, members...)
+	})
+}
+
+func (f *FakeCache) BumpVersion(ctx context.Context, group string) error {
+	return f.do(ctx, "BumpVersion", []string{group}, nil, func() error {
+		// Any group is versioned, the fake is not configured with groups
+		_, err := f.cache.dropGroup(ctx, group)
+		return err
+	})
+}
@@ -0,0 +1,130 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/saigontechnology/go-shared-packages/cache"
+)
+
+func TestFakeCache_Calls(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	c := cache.NewFakeCache()
+
+	r.NoError(c.Set(ctx, "a", "value", time.Minute, nil))
+	r.NoError(c.MSet(ctx, []cache.Entry{{Key: "b", Data: 1, Expire: time.Hour}}, nil))
+	var value string
+	r.NoError(c.Get(ctx, "a", &value, nil))
+	r.Equal("value", value)
+	r.ErrorIs(c.Get(ctx, "missing", &value, nil), cache.ErrCacheMiss)
+
+	calls := c.Calls("Get")
+	r.Len(calls, 2)
+	r.Equal([]string{"missing"}, calls[1].Keys)
+	r.ErrorIs(calls[1].Err, cache.ErrCacheMiss)
+	r.Len(c.Calls(""), 4)
+	c.AssertCalled(t, "Get", "a")
+	c.AssertNotCalled(t, "Del", "a")
+	c.AssertSet(t, "a", time.Minute)
+	c.AssertSet(t, "b", time.Hour)
+
+	c.Reset()
+	r.Empty(c.Calls(""))
+	r.NoError(c.Get(ctx, "a", &value, nil))
+}
+
+func TestFakeCache_FailOn(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	errInjected := errors.New("injected")
+
+	testCases := []struct {
+		name    string
+		method  string
+		key     string
+		wantErr error
+	}{
+		{
+			name:    "Method and key match",
+			method:  "Set",
+			key:     "a",
+			wantErr: errInjected,
+		},
+		{
+			name:    "All methods",
+			key:     "a",
+			wantErr: errInjected,
+		},
+		{
+			name:    "All keys",
+			method:  "Set",
+			wantErr: errInjected,
+		},
+		{
+			name:   "Other key",
+			method: "Set",
+			key:    "b",
+		},
+		{
+			name:   "Other method",
+			method: "Get",
+			key:    "a",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			c := cache.NewFakeCache()
+			c.FailOn(tc.method, tc.key, errInjected)
+
+			err := c.Set(ctx, "a", "value", 0, nil)
+			if tc.wantErr != nil {
+				r.ErrorIs(err, tc.wantErr)
+				// A failed call does not store anything
+				c.Reset()
+				var value string
+				r.ErrorIs(c.Get(ctx, "a", &value, nil), cache.ErrCacheMiss)
+				return
+			}
+			r.NoError(err)
+		})
+	}
+}
+
+func TestFakeCache_DelayOn(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	c := cache.NewFakeCache()
+	c.DelayOn("Get", "", time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var value string
+	r.ErrorIs(c.Get(ctx, "a", &value, nil), context.DeadlineExceeded)
+	r.NoError(c.Set(ctx, "a", "value", 0, nil))
+}
+
+func TestFakeCache_BumpVersion(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	c := cache.NewFakeCache()
+
+	r.NoError(c.Set(ctx, "users:1", "alice", time.Minute, nil))
+	r.NoError(c.Set(ctx, "orders:1", "book", time.Minute, nil))
+	r.NoError(c.BumpVersion(ctx, "users"))
+	c.AssertCalled(t, "BumpVersion", "users")
+
+	var value string
+	r.ErrorIs(c.Get(ctx, "users:1", &value, nil), cache.ErrCacheMiss)
+	r.NoError(c.Get(ctx, "orders:1", &value, nil))
+}
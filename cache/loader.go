@@ -0,0 +1,178 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	defaultLoadTimeout     = 30 * time.Second
+	loadLockKeyPrefix      = "load:"
+	loadLockWaitIntervals  = 10
+	minLoadLockWaitBackoff = 10 * time.Millisecond
+)
+
+// loadLocker is implemented by caches which can lock a key across processes.
+type loadLocker interface {
+	tryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error)
+}
+
+// Loader loads a value from the source of truth, usually a database, on a cache miss.
+type Loader[T any] func(ctx context.Context) (T, error)
+
+// GetOrLoad returns the cached value of key. On a miss, loader is called and its result is cached for ttl.
+// Concurrent misses of the same key in a process share one loader call, which runs until it returns or
+// the timeout of WithLoadTimeout elapses. A caller whose ctx is done stops waiting for it with ctx.Err().
+// With WithLoadLock, concurrent misses across processes are collapsed as well.
+// With WithStaleWhileRevalidate, a stale value is returned while one goroutine reloads it in the background.
+func (t *Typed[T]) GetOrLoad(
+	ctx context.Context,
+	key string,
+	ttl time.Duration,
+	tag *string,
+	loader Loader[T],
+) (T, error) {
+	e, err := t.getEntry(ctx, key, tag)
+	if err == nil {
+		var value T
+		value, err = t.decode(e)
+		if err == nil || errors.Is(err, ErrKnownAbsent) {
+			if err == nil && e.stale(time.Now()) {
+				t.refresh(ctx, key, ttl, tag, loader)
+			}
+			return value, err
+		}
+	}
+	if !errors.Is(err, ErrCacheMiss) {
+		log.Printf("[Cache] could not get key %s, loading it. Error: %s", key, err.Error())
+	}
+
+	loaded := t.group.DoChan(key, func() (interface{}, error) {
+		return t.sharedLoad(ctx, key, ttl, tag, loader)
+	})
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	case result := <-loaded:
+		if result.Err != nil {
+			var zero T
+			return zero, result.Err
+		}
+		return result.Val.(T), nil
+	}
+}
+
+// sharedLoad runs load for all callers waiting for key, so the load is not cancelled with the first of them.
+func (t *Typed[T]) sharedLoad(
+	ctx context.Context,
+	key string,
+	ttl time.Duration,
+	tag *string,
+	loader Loader[T],
+) (T, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), t.cfg.loadTimeout)
+	defer cancel()
+	return t.load(ctx, key, ttl, tag, loader)
+}
+
+// refresh reloads a stale key in the background, it does nothing when the key is already being loaded.
+func (t *Typed[T]) refresh(
+	ctx context.Context,
+	key string,
+	ttl time.Duration,
+	tag *string,
+	loader Loader[T],
+) {
+	// The result is buffered in the returned channel, so it does not have to be received
+	t.group.DoChan(key, func() (interface{}, error) {
+		value, err := t.sharedLoad(ctx, key, ttl, tag, loader)
+		if err != nil && !errors.Is(err, ErrKnownAbsent) {
+			log.Printf("[Cache] could not refresh key %s. Error: %s", key, err.Error())
+		}
+		return value, err
+	})
+}
+
+func (t *Typed[T]) load(
+	ctx context.Context,
+	key string,
+	ttl time.Duration,
+	tag *string,
+	loader Loader[T],
+) (T, error) {
+	locker, ok := t.cache.(loadLocker)
+	if !ok || t.cfg.loadLockTTL <= 0 {
+		return t.loadAndSet(ctx, key, ttl, tag, loader)
+	}
+
+	unlock, acquired, err := locker.tryLock(ctx, loadLockKeyPrefix+key, t.cfg.loadLockTTL)
+	if err != nil {
+		log.Printf("[Cache] could not lock key %s, loading it. Error: %s", key, err.Error())
+		return t.loadAndSet(ctx, key, ttl, tag, loader)
+	}
+	if !acquired {
+		// Another process is loading the key, wait for its result
+		if value, err := t.waitForValue(ctx, key, tag); err == nil || errors.Is(err, ErrKnownAbsent) {
+			return value, err
+		}
+		return t.loadAndSet(ctx, key, ttl, tag, loader)
+	}
+	defer unlock()
+
+	// The key may have been loaded while we were waiting for the lock
+	if value, err := t.getFresh(ctx, key, tag); err == nil || errors.Is(err, ErrKnownAbsent) {
+		return value, err
+	}
+	return t.loadAndSet(ctx, key, ttl, tag, loader)
+}
+
+func (t *Typed[T]) loadAndSet(
+	ctx context.Context,
+	key string,
+	ttl time.Duration,
+	tag *string,
+	loader Loader[T],
+) (T, error) {
+	txn := t.metric.NewCacheLoadLatencyTransaction(tag)
+	value, err := loader(ctx)
+	txn.End()
+	if errors.Is(err, ErrKnownAbsent) && t.cfg.negativeTTL > 0 {
+		if err := t.SetAbsent(ctx, key, tag); err != nil && !errors.Is(err, ErrCircuitOpen) {
+			log.Printf("[Cache] could not set absent key %s. Error: %s", key, err.Error())
+		}
+	}
+	if err != nil {
+		return value, err
+	}
+
+	// The breaker already logged that Redis is down, values loaded meanwhile are not cached
+	if err := t.Set(ctx, key, value, ttl, tag); err != nil && !errors.Is(err, ErrCircuitOpen) {
+		log.Printf("[Cache] could not set loaded key %s. Error: %s", key, err.Error())
+	}
+	return value, nil
+}
+
+func (t *Typed[T]) waitForValue(ctx context.Context, key string, tag *string) (T, error) {
+	interval := t.cfg.loadLockTTL / loadLockWaitIntervals
+	if interval < minLoadLockWaitBackoff {
+		interval = minLoadLockWaitBackoff
+	}
+	deadline := time.Now().Add(t.cfg.loadLockTTL)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		case <-ticker.C:
+			value, err := t.getFresh(ctx, key, tag)
+			if err == nil || errors.Is(err, ErrKnownAbsent) || time.Now().After(deadline) {
+				return value, err
+			}
+		}
+	}
+}
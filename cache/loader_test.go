@@ -0,0 +1,125 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/saigontechnology/go-shared-packages/cache"
+)
+
+func TestTyped_GetOrLoad(t *testing.T) {
+	t.Parallel()
+	errLoad := errors.New("load error")
+	testCases := []struct {
+		name          string
+		instances     int
+		options       []cache.TypedOption
+		loadErr       error
+		expectedErr   error
+		expectedCalls int32
+	}{
+		{
+			name:          "Concurrent misses in a process",
+			instances:     1,
+			expectedCalls: 1,
+		},
+		{
+			name:          "Concurrent misses across processes",
+			instances:     3,
+			options:       []cache.TypedOption{cache.WithLoadLock(time.Second)},
+			expectedCalls: 1,
+		},
+		{
+			name:          "Loader error",
+			instances:     1,
+			loadErr:       errLoad,
+			expectedErr:   errLoad,
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			a := assert.New(t)
+			c := cache.NewMiniRedisForTest(t)
+			instances := make([]*cache.Typed[string], tc.instances)
+			for i := range instances {
+				instances[i] = cache.NewTyped[string](c, nil, tc.options...)
+			}
+
+			var calls atomic.Int32
+			loader := func(ctx context.Context) (string, error) {
+				calls.Add(1)
+				time.Sleep(100 * time.Millisecond)
+				return "value", tc.loadErr
+			}
+
+			var wg sync.WaitGroup
+			for i := 0; i < 10*tc.instances; i++ {
+				wg.Add(1)
+				go func(typed *cache.Typed[string]) {
+					defer wg.Done()
+					value, err := typed.GetOrLoad(context.Background(), "key", time.Minute, nil, loader)
+					if tc.expectedErr != nil {
+						a.ErrorIs(err, tc.expectedErr)
+						return
+					}
+					a.NoError(err)
+					a.Equal("value", value)
+				}(instances[i%tc.instances])
+			}
+			wg.Wait()
+			require.Equal(t, tc.expectedCalls, calls.Load())
+		})
+	}
+}
+
+func TestTyped_GetOrLoadCancelled(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	typed := cache.NewTyped[string](cache.NewMiniRedisForTest(t), nil, cache.WithLoadTimeout(time.Second))
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (string, error) {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-release:
+			return "value", nil
+		}
+	}
+
+	// The first caller gives up, the load it started goes on for the caller waiting with it
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := typed.GetOrLoad(ctx, "key", time.Minute, nil, loader)
+		first <- err
+	}()
+	second := make(chan string)
+	go func() {
+		value, err := typed.GetOrLoad(context.Background(), "key", time.Minute, nil, loader)
+		assert.NoError(t, err)
+		second <- value
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	r.ErrorIs(<-first, context.Canceled)
+	close(release)
+	r.Equal("value", <-second)
+
+	// The load is bounded by its own timeout
+	_, err := typed.GetOrLoad(context.Background(), "other", time.Minute, nil, func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	r.ErrorIs(err, context.DeadlineExceeded)
+}
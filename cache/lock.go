@@ -0,0 +1,229 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	lockKeyPrefix      = "lock:"
+	lockRetryInterval  = 50 * time.Millisecond
+	lockExtendDivision = 3
+	// minLockTTL is the precision of lock expirations in Redis
+	minLockTTL = time.Millisecond
+)
+
+var (
+	ErrLockNotAcquired = errors.New("[Cache] lock is held by another owner")
+	ErrLockNotHeld     = errors.New("[Cache] lock is not held anymore")
+	ErrInvalidLockTTL  = fmt.Errorf("[Cache] lock ttl must be at least %s", minLockTTL)
+)
+
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+var extendLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// Locker provides mutual exclusion across instances of a service.
+type Locker interface {
+	// TryLock tries to acquire the lock of key once, it returns ErrLockNotAcquired when another owner holds it.
+	// The lock expires after ttl unless its lease is extended, which happens automatically until Unlock is called.
+	// A ttl under a millisecond is rejected with ErrInvalidLockTTL.
+	TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, error)
+	// Lock retries TryLock until the lock is acquired, timeout elapses or ctx is done.
+	// It returns ErrLockNotAcquired when timeout elapsed and the error of ctx when ctx is done.
+	Lock(ctx context.Context, key string, ttl, timeout time.Duration) (Lock, error)
+}
+
+type Lock interface {
+	Key() string
+	// Unlock releases the lock, it returns ErrLockNotHeld when the lock expired or was taken over.
+	Unlock(ctx context.Context) error
+	// Lost is closed when the lease could not be extended, the critical section is not exclusive anymore.
+	Lost() <-chan struct{}
+}
+
+type redisLocker struct {
+	cache *redisCache
+}
+
+// NewLocker returns a Locker using the connection and namespace of a Redis-backed Cache,
+// like the caches returned by RedisCache, TieredCache and NewMiniRedisForTest.
+func NewLocker(c Cache) (Locker, error) {
+	switch c := c.(type) {
+	case *redisCache:
+		return newRedisLocker(c), nil
+	case *breakerCache:
+		return NewLocker(c.cache)
+	case *tieredCache:
+		return newRedisLocker(c.remote), nil
+	default:
+		return nil, fmt.Errorf("[Cache] %T does not support locks", c)
+	}
+}
+
+func newRedisLocker(cache *redisCache) *redisLocker {
+	return &redisLocker{cache: cache}
+}
+
+func (l *redisLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	if ttl < minLockTTL {
+		return nil, ErrInvalidLockTTL
+	}
+	s := l.cache.newrelicRedisSegment(ctx, "TryLock")
+	defer s.End()
+	lockKey := l.cache.internalKey(lockKeyPrefix + key)
+	token := uuid.NewString()
+	acquired, err := l.cache.client.SetNX(ctx, lockKey, token, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, ErrLockNotAcquired
+	}
+
+	lock := &redisLock{
+		locker:  l,
+		key:     key,
+		lockKey: lockKey,
+		token:   token,
+		ttl:     ttl,
+		stop:    make(chan struct{}),
+		lost:    make(chan struct{}),
+	}
+	go lock.keepAlive()
+	return lock, nil
+}
+
+func (l *redisLocker) Lock(ctx context.Context, key string, ttl, timeout time.Duration) (Lock, error) {
+	if ttl < minLockTTL {
+		return nil, ErrInvalidLockTTL
+	}
+	parent := ctx
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	for {
+		lock, err := l.TryLock(ctx, key, ttl)
+		if err != nil && ctx.Err() != nil {
+			// The attempt was interrupted by the caller or by the timeout
+			return nil, lockWaitError(parent)
+		}
+		if !errors.Is(err, ErrLockNotAcquired) {
+			return lock, err
+		}
+
+		// Jitter avoids waiters retrying at the same time
+		//nolint:gosec
+		wait := lockRetryInterval + time.Duration(rand.Int63n(int64(lockRetryInterval)))
+		select {
+		case <-ctx.Done():
+			return nil, lockWaitError(parent)
+		case <-time.After(wait):
+		}
+	}
+}
+
+// lockWaitError tells a caller who gave up apart from a lock still held by another owner when the timeout elapsed.
+func lockWaitError(parent context.Context) error {
+	if err := parent.Err(); err != nil {
+		return err
+	}
+	return ErrLockNotAcquired
+}
+
+type redisLock struct {
+	locker   *redisLocker
+	key      string
+	lockKey  string
+	token    string
+	ttl      time.Duration
+	stopOnce sync.Once
+	stop     chan struct{}
+	lost     chan struct{}
+}
+
+func (l *redisLock) Key() string {
+	return l.key
+}
+
+func (l *redisLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *redisLock) Unlock(ctx context.Context) error {
+	l.stopOnce.Do(func() {
+		close(l.stop)
+	})
+	s := l.locker.cache.newrelicRedisSegment(ctx, "Unlock")
+	defer s.End()
+	released, err := releaseLockScript.Run(ctx, l.locker.cache.client, []string{l.lockKey}, l.token).Int64()
+	if err != nil {
+		return err
+	}
+	if released == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// keepAlive extends the lease until the lock is released. The lock is lost when it was taken over
+// or when it could not be extended before its lease ended.
+func (l *redisLock) keepAlive() {
+	interval := l.ttl / lockExtendDivision
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	leaseEnd := time.Now().Add(l.ttl)
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			extended, err := l.extend()
+			if err == nil && !extended {
+				close(l.lost)
+				return
+			}
+			if err != nil {
+				log.Printf("[Cache] could not extend lock %s. Error: %s", l.key, err.Error())
+				if time.Now().After(leaseEnd) {
+					close(l.lost)
+					return
+				}
+				continue
+			}
+			leaseEnd = time.Now().Add(l.ttl)
+		}
+	}
+}
+
+func (l *redisLock) extend() (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), l.ttl/lockExtendDivision)
+	defer cancel()
+	s := l.locker.cache.newrelicRedisSegment(ctx, "ExtendLock")
+	defer s.End()
+	extended, err := extendLockScript.Run(
+		ctx,
+		l.locker.cache.client,
+		[]string{l.lockKey},
+		l.token,
+		l.ttl.Milliseconds(),
+	).Int64()
+	return extended == 1, err
+}
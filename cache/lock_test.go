@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisLocker_TryLock(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	locker := newRedisLocker(newMiniRedisForTest(t, miniredis.RunT(t)))
+
+	lock, err := locker.TryLock(ctx, "job", time.Second)
+	r.NoError(err)
+	_, err = locker.TryLock(ctx, "job", time.Second)
+	r.ErrorIs(err, ErrLockNotAcquired)
+	r.NoError(lock.Unlock(ctx))
+	r.ErrorIs(lock.Unlock(ctx), ErrLockNotHeld)
+
+	lock, err = locker.TryLock(ctx, "job", time.Second)
+	r.NoError(err)
+	r.NoError(lock.Unlock(ctx))
+}
+
+func TestRedisLocker_Lock(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	locker := newRedisLocker(newMiniRedisForTest(t, miniredis.RunT(t)))
+
+	holder, err := locker.TryLock(ctx, "job", time.Second)
+	r.NoError(err)
+	_, err = locker.Lock(ctx, "job", time.Second, 100*time.Millisecond)
+	r.ErrorIs(err, ErrLockNotAcquired)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		//nolint: errcheck
+		holder.Unlock(ctx)
+	}()
+	lock, err := locker.Lock(ctx, "job", time.Second, time.Second)
+	r.NoError(err)
+	r.NoError(lock.Unlock(ctx))
+
+	// The timeout elapses while the lock is being acquired
+	_, err = locker.Lock(ctx, "job", time.Second, time.Nanosecond)
+	r.ErrorIs(err, ErrLockNotAcquired)
+
+	// The caller gives up before the timeout
+	holder, err = locker.TryLock(ctx, "job", time.Second)
+	r.NoError(err)
+	cancelled, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = locker.Lock(cancelled, "job", time.Second, time.Minute)
+	r.ErrorIs(err, context.DeadlineExceeded)
+	r.NoError(holder.Unlock(ctx))
+}
+
+func TestRedisLocker_InvalidTTL(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	locker := newRedisLocker(newMiniRedisForTest(t, miniredis.RunT(t)))
+
+	for _, ttl := range []time.Duration{-time.Second, 0, time.Nanosecond, time.Millisecond - 1} {
+		ttl := ttl
+		t.Run(ttl.String(), func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			_, err := locker.TryLock(ctx, "job", ttl)
+			r.ErrorIs(err, ErrInvalidLockTTL)
+			_, err = locker.Lock(ctx, "job", ttl, time.Second)
+			r.ErrorIs(err, ErrInvalidLockTTL)
+		})
+	}
+}
+
+func TestRedisLocker_KeepAlive(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	locker := newRedisLocker(newMiniRedisForTest(t, mr))
+
+	lock, err := locker.TryLock(ctx, "job", 300*time.Millisecond)
+	r.NoError(err)
+	lockKey := lock.(*redisLock).lockKey
+	// The lease is extended before it ends
+	for i := 0; i < 2; i++ {
+		time.Sleep(150 * time.Millisecond)
+		mr.FastForward(250 * time.Millisecond)
+		r.True(mr.Exists(lockKey))
+	}
+
+	// The lock is lost when another owner takes it over
+	r.NoError(mr.Set(lockKey, "another-owner"))
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		r.Fail("lock is not lost")
+	}
+	r.ErrorIs(lock.Unlock(ctx), ErrLockNotHeld)
+}
@@ -0,0 +1,575 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/saigontechnology/go-shared-packages/prometheus"
+)
+
+var errWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+var errNotInteger = errors.New("ERR value is not an integer or out of range")
+
+type memoryEntry struct {
+	value    []byte
+	hash     map[string][]byte
+	zset     map[string]float64
+	expireAt time.Time
+}
+
+// isString tells whether the entry holds a plain value rather than a hash or a sorted set.
+func (e *memoryEntry) isString() bool {
+	return e.hash == nil && e.zset == nil
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && !now.Before(e.expireAt)
+}
+
+func (e *memoryEntry) setExpire(now time.Time, expire time.Duration) {
+	if expire > 0 {
+		e.expireAt = now.Add(expire)
+	} else {
+		e.expireAt = time.Time{}
+	}
+}
+
+// memoryCache is an in-process Cache with size-bounded eviction and per-entry TTL.
+// Expired entries are removed when they are accessed, otherwise they are evicted like any other key.
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	tags    map[string]map[string]struct{}
+	// keyTags are the tags of each key, so a deleted key is also removed from its tag sets
+	keyTags    map[string]map[string]struct{}
+	evictor    evictor
+	maxEntries int
+	metric     prometheus.CacheMetric
+	codec      *valueCodec
+	// versions only tells which groups are versioned, versions are not kept in process
+	versions *groupVersions
+}
+
+func newMemoryCache(cfg *Config) (*memoryCache, error) {
+	c, err := NewMemoryCache(cfg.MemoryMaxEntries, EvictionPolicy(cfg.MemoryEvictionPolicy))
+	if err != nil {
+		return nil, err
+	}
+	codec, err := newValueCodec(cfg.Codec, cfg.Compression, cfg.CompressionThreshold)
+	if err != nil {
+		return nil, err
+	}
+	m := c.(*memoryCache)
+	m.codec = codec
+	m.versions = newGroupVersions(cfg.VersionedGroups, cfg.VersionLocalTTL)
+	return m, nil
+}
+
+// NewMemoryCache creates an in-process Cache holding at most maxEntries keys, 0 means unlimited.
+// BumpVersion drops the keys of versionedGroups and rejects other groups.
+func NewMemoryCache(maxEntries int, policy EvictionPolicy, versionedGroups ...string) (Cache, error) {
+	e, err := newEvictor(policy)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryCache{
+		entries:    make(map[string]*memoryEntry),
+		tags:       make(map[string]map[string]struct{}),
+		keyTags:    make(map[string]map[string]struct{}),
+		evictor:    e,
+		maxEntries: maxEntries,
+		metric:     prometheus.GetCacheMetric(),
+		codec:      defaultValueCodec(),
+		versions:   newGroupVersions(versionedGroups, 0),
+	}, nil
+}
+
+func (m *memoryCache) Get(ctx context.Context, key string, data interface{}, tag *string) error {
+	txn := m.metric.NewCacheGetLatencyTransaction(tag)
+	defer txn.End()
+	value, err := m.getBytes(key)
+	if err == nil {
+		err = scanValue(value, data)
+	}
+	if err == nil {
+		m.metric.CountCacheHit(tag)
+	} else {
+		m.metric.CountCacheMiss(tag)
+	}
+	return err
+}
+
+func (m *memoryCache) Set(
+	ctx context.Context,
+	key string,
+	data interface{},
+	expire time.Duration,
+	tag *string,
+) error {
+	txn := m.metric.NewCacheSetLatencyTransaction(tag)
+	defer txn.End()
+	value, err := m.codec.encode(data)
+	if err != nil {
+		return err
+	}
+	m.setBytes(key, value, expire)
+	return nil
+}
+
+func (m *memoryCache) HGet(ctx context.Context, key, field string, data interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := m.lookup(key)
+	if entry == nil {
+		return ErrCacheMiss
+	}
+	if entry.hash == nil {
+		return errWrongType
+	}
+	value, ok := entry.hash[field]
+	if !ok {
+		return ErrCacheMiss
+	}
+	return scanValue(value, data)
+}
+
+func (m *memoryCache) HSet(
+	ctx context.Context,
+	key, field string,
+	data interface{},
+	expire time.Duration,
+) error {
+	value, err := m.codec.encode(data)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := m.lookup(key)
+	if entry == nil {
+		entry = &memoryEntry{hash: make(map[string][]byte)}
+		m.store(key, entry)
+	}
+	if entry.hash == nil {
+		return errWrongType
+	}
+	entry.hash[field] = value
+	entry.setExpire(time.Now(), expire)
+	return nil
+}
+
+func (m *memoryCache) RemoveHashKey(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := m.lookup(key)
+	if entry == nil {
+		return nil
+	}
+	if entry.hash == nil {
+		return errWrongType
+	}
+	// Redis removes a hash when its last field is deleted
+	m.delete(key)
+	return nil
+}
+
+func (m *memoryCache) DelKeysWithPattern(ctx context.Context, pattern string) error {
+	_, err := m.UnlinkKeysWithPattern(ctx, pattern)
+	return err
+}
+
+func (m *memoryCache) UnlinkKeysWithPattern(ctx context.Context, pattern string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var deleted int64
+	for key := range m.entries {
+		if matchPattern(pattern, key) {
+			m.delete(key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *memoryCache) UnlinkKeysWithPatternAsync(ctx context.Context, pattern string) *Deletion {
+	return runDeletion(ctx, func(ctx context.Context, progress func(deleted int64)) error {
+		deleted, err := m.UnlinkKeysWithPattern(ctx, pattern)
+		progress(deleted)
+		return err
+	})
+}
+
+func (m *memoryCache) Del(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.delete(key)
+	return nil
+}
+
+func (m *memoryCache) SetWithTags(
+	ctx context.Context,
+	key string,
+	data interface{},
+	expire time.Duration,
+	tag *string,
+	invalidationTags ...string,
+) error {
+	if err := m.Set(ctx, key, data, expire, tag); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.entries[key]; !ok {
+		// The key was already evicted
+		return nil
+	}
+	for _, invalidationTag := range invalidationTags {
+		addToSet(m.tags, invalidationTag, key)
+		addToSet(m.keyTags, key, invalidationTag)
+	}
+	return nil
+}
+
+func (m *memoryCache) InvalidateTags(ctx context.Context, invalidationTags ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, invalidationTag := range invalidationTags {
+		for key := range m.tags[invalidationTag] {
+			m.delete(key)
+		}
+		delete(m.tags, invalidationTag)
+	}
+	return nil
+}
+
+func (m *memoryCache) MGet(
+	ctx context.Context,
+	keys []string,
+	data []interface{},
+	tag *string,
+) ([]bool, error) {
+	if len(keys) != len(data) {
+		return nil, fmt.Errorf("[Cache] MGet got %d keys but %d destinations", len(keys), len(data))
+	}
+	txn := m.metric.NewCacheGetLatencyTransaction(tag)
+	defer txn.End()
+	return scanValues(m.getBytesMany(keys), data, tag, m.metric)
+}
+
+func (m *memoryCache) MSet(ctx context.Context, entries []Entry, tag *string) error {
+	txn := m.metric.NewCacheSetLatencyTransaction(tag)
+	defer txn.End()
+	values := make([][]byte, len(entries))
+	for i, entry := range entries {
+		value, err := m.codec.encode(entry.Data)
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+	for i, entry := range entries {
+		m.setBytes(entry.Key, values[i], entry.Expire)
+	}
+	return nil
+}
+
+func (m *memoryCache) MDel(ctx context.Context, keys ...string) error {
+	m.delKeys(keys)
+	return nil
+}
+
+func (m *memoryCache) HGetAll(ctx context.Context, key string) (Hash, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := m.lookup(key)
+	if entry == nil {
+		return Hash{}, nil
+	}
+	if entry.hash == nil {
+		return nil, errWrongType
+	}
+	hash := make(Hash, len(entry.hash))
+	for field, value := range entry.hash {
+		hash[field] = value
+	}
+	return hash, nil
+}
+
+func (m *memoryCache) HMSet(
+	ctx context.Context,
+	key string,
+	values map[string]interface{},
+	expire time.Duration,
+) error {
+	fields := make(map[string][]byte, len(values))
+	for field, data := range values {
+		value, err := m.codec.encode(data)
+		if err != nil {
+			return err
+		}
+		fields[field] = value
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := m.lookup(key)
+	if entry == nil {
+		entry = &memoryEntry{hash: make(map[string][]byte)}
+		m.store(key, entry)
+	}
+	if entry.hash == nil {
+		return errWrongType
+	}
+	for field, value := range fields {
+		entry.hash[field] = value
+	}
+	if expire > 0 {
+		entry.setExpire(time.Now(), expire)
+	}
+	return nil
+}
+
+func (m *memoryCache) HDel(ctx context.Context, key string, fields ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := m.lookup(key)
+	if entry == nil {
+		return nil
+	}
+	if entry.hash == nil {
+		return errWrongType
+	}
+	for _, field := range fields {
+		delete(entry.hash, field)
+	}
+	if len(entry.hash) == 0 {
+		m.delete(key)
+	}
+	return nil
+}
+
+func (m *memoryCache) Incr(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	return m.IncrBy(ctx, key, 1, expire)
+}
+
+func (m *memoryCache) IncrBy(ctx context.Context, key string, value int64, expire time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := m.lookup(key)
+	if entry == nil {
+		entry = &memoryEntry{value: []byte("0")}
+		m.store(key, entry)
+	}
+	if !entry.isString() {
+		return 0, errWrongType
+	}
+	current, err := strconv.ParseInt(string(entry.value), 10, 64)
+	if err != nil {
+		return 0, errNotInteger
+	}
+	current += value
+	entry.value = strconv.AppendInt(nil, current, 10)
+	if expire > 0 && entry.expireAt.IsZero() {
+		entry.setExpire(time.Now(), expire)
+	}
+	return current, nil
+}
+
+func (m *memoryCache) Decr(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	return m.IncrBy(ctx, key, -1, expire)
+}
+
+func (m *memoryCache) Expire(ctx context.Context, key string, expire time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := m.lookup(key)
+	if entry == nil {
+		return ErrCacheMiss
+	}
+	entry.setExpire(time.Now(), expire)
+	return nil
+}
+
+func (m *memoryCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := m.lookup(key)
+	if entry == nil {
+		return 0, ErrCacheMiss
+	}
+	if entry.expireAt.IsZero() {
+		return NoExpiration, nil
+	}
+	return time.Until(entry.expireAt), nil
+}
+
+func (m *memoryCache) ZAdd(ctx context.Context, key string, expire time.Duration, members ...ZMember) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := m.lookup(key)
+	if entry == nil {
+		entry = &memoryEntry{zset: make(map[string]float64)}
+		m.store(key, entry)
+	}
+	if entry.zset == nil {
+		return errWrongType
+	}
+	for _, member := range members {
+		entry.zset[member.Member] = member.Score
+	}
+	if expire > 0 {
+		entry.setExpire(time.Now(), expire)
+	}
+	return nil
+}
+
+func (m *memoryCache) ZRangeByScore(ctx context.Context, key string, by ZRangeBy) ([]ZMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := m.lookup(key)
+	if entry == nil {
+		return rangeByScore(nil, by)
+	}
+	if entry.zset == nil {
+		return nil, errWrongType
+	}
+	return rangeByScore(entry.zset, by)
+}
+
+func (m *memoryCache) ZRem(ctx context.Context, key string, members ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := m.lookup(key)
+	if entry == nil {
+		return nil
+	}
+	if entry.zset == nil {
+		return errWrongType
+	}
+	for _, member := range members {
+		delete(entry.zset, member)
+	}
+	if len(entry.zset) == 0 {
+		m.delete(key)
+	}
+	return nil
+}
+
+// BumpVersion drops the keys of the group, an in-process cache does not need to keep them until they expire.
+func (m *memoryCache) BumpVersion(ctx context.Context, group string) error {
+	if !m.versions.versioned(group) {
+		return fmt.Errorf("[Cache] group %s is not versioned", group)
+	}
+	_, err := m.dropGroup(ctx, group)
+	return err
+}
+
+// dropGroup deletes the keys of a group, whether it is versioned or not.
+func (m *memoryCache) dropGroup(ctx context.Context, group string) (int64, error) {
+	return m.UnlinkKeysWithPattern(ctx, group+":*")
+}
+
+// getBytesMany returns nil for missing keys and keys which do not hold a plain value.
+func (m *memoryCache) getBytesMany(keys []string) [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		if entry := m.lookup(key); entry != nil && entry.isString() {
+			values[i] = entry.value
+		}
+	}
+	return values
+}
+
+func (m *memoryCache) delKeys(keys []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		m.delete(key)
+	}
+}
+
+func (m *memoryCache) getBytes(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := m.lookup(key)
+	if entry == nil {
+		return nil, ErrCacheMiss
+	}
+	if !entry.isString() {
+		return nil, errWrongType
+	}
+	return entry.value, nil
+}
+
+func (m *memoryCache) setBytes(key string, value []byte, expire time.Duration) {
+	entry := &memoryEntry{value: value}
+	entry.setExpire(time.Now(), expire)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store(key, entry)
+}
+
+// lookup returns a live entry and records the access, m.mu must be held.
+func (m *memoryCache) lookup(key string) *memoryEntry {
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil
+	}
+	if entry.expired(time.Now()) {
+		m.delete(key)
+		return nil
+	}
+	m.evictor.touch(key)
+	return entry
+}
+
+// store adds or replaces an entry and evicts keys when the cache is full, m.mu must be held.
+func (m *memoryCache) store(key string, entry *memoryEntry) {
+	if _, ok := m.entries[key]; !ok && m.maxEntries > 0 {
+		for len(m.entries) >= m.maxEntries {
+			victim, ok := m.evictor.victim()
+			if !ok {
+				break
+			}
+			m.delete(victim)
+		}
+	}
+	m.entries[key] = entry
+	m.evictor.add(key)
+}
+
+// delete removes key with its tags, tag sets without keys are dropped, m.mu must be held.
+func (m *memoryCache) delete(key string) {
+	delete(m.entries, key)
+	m.evictor.remove(key)
+	for invalidationTag := range m.keyTags[key] {
+		keys := m.tags[invalidationTag]
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(m.tags, invalidationTag)
+		}
+	}
+	delete(m.keyTags, key)
+}
+
+func addToSet(sets map[string]map[string]struct{}, name, member string) {
+	set, ok := sets[name]
+	if !ok {
+		set = make(map[string]struct{})
+		sets[name] = set
+	}
+	set[member] = struct{}{}
+}
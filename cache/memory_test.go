@@ -0,0 +1,126 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/saigontechnology/go-shared-packages/cache"
+)
+
+func TestMemoryCache_DelKeysWithPattern(t *testing.T) {
+	t.Parallel()
+	keys := []string{"user:1", "user:2", "user:10", "users", "order:1", "a*b", "ab", "hello", "hallo", "hxllo"}
+	patterns := []string{"user:*", "user:?", "h[ae]llo", "h[^e]llo", "h[a-b]llo", "a\\*b", "*", "nothing"}
+
+	for _, pattern := range patterns {
+		pattern := pattern
+		t.Run(pattern, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			ctx := context.Background()
+			memory, err := cache.NewMemoryCache(0, cache.EvictionPolicyLRU)
+			r.NoError(err)
+			redis := cache.NewMiniRedisForTest(t)
+			for _, c := range []cache.Cache{memory, redis} {
+				for _, key := range keys {
+					r.NoError(c.Set(ctx, key, key, time.Minute, nil))
+				}
+				r.NoError(c.DelKeysWithPattern(ctx, pattern))
+			}
+
+			// The memory cache must delete the same keys as Redis does
+			for _, key := range keys {
+				var memoryValue, redisValue string
+				memoryErr := memory.Get(ctx, key, &memoryValue, nil)
+				redisErr := redis.Get(ctx, key, &redisValue, nil)
+				r.Equal(redisErr, memoryErr, key)
+				r.Equal(redisValue, memoryValue, key)
+			}
+		})
+	}
+}
+
+func TestMemoryCache_Eviction(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name    string
+		policy  cache.EvictionPolicy
+		evicted string
+	}{
+		{
+			name:    "LRU evicts the least recently used key",
+			policy:  cache.EvictionPolicyLRU,
+			evicted: "b",
+		},
+		{
+			name:    "LFU evicts the least frequently used key",
+			policy:  cache.EvictionPolicyLFU,
+			evicted: "c",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			ctx := context.Background()
+			c, err := cache.NewMemoryCache(3, tc.policy)
+			r.NoError(err)
+			var value string
+			r.NoError(c.Set(ctx, "a", "a", 0, nil))
+			r.NoError(c.Set(ctx, "b", "b", 0, nil))
+			r.NoError(c.Get(ctx, "b", &value, nil))
+			r.NoError(c.Get(ctx, "a", &value, nil))
+			r.NoError(c.Set(ctx, "c", "c", 0, nil))
+			r.NoError(c.Set(ctx, "d", "d", 0, nil))
+
+			for _, key := range []string{"a", "b", "c", "d"} {
+				err := c.Get(ctx, key, &value, nil)
+				if key == tc.evicted {
+					r.ErrorIs(err, cache.ErrCacheMiss, key)
+				} else {
+					r.NoError(err, key)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryCache_Expiration(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	c, err := cache.NewMemoryCache(0, cache.EvictionPolicyLRU)
+	r.NoError(err)
+	r.NoError(c.Set(ctx, "key", 1, 50*time.Millisecond, nil))
+	r.NoError(c.HSet(ctx, "hash", "field", 1, 50*time.Millisecond))
+	var value int
+	r.NoError(c.Get(ctx, "key", &value, nil))
+	r.Equal(1, value)
+	r.NoError(c.HGet(ctx, "hash", "field", &value))
+
+	time.Sleep(60 * time.Millisecond)
+	r.ErrorIs(c.Get(ctx, "key", &value, nil), cache.ErrCacheMiss)
+	r.ErrorIs(c.HGet(ctx, "hash", "field", &value), cache.ErrCacheMiss)
+}
+
+func TestMemoryCache_EvictedKeyLeavesItsTags(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	c, err := cache.NewMemoryCache(1, cache.EvictionPolicyLRU)
+	r.NoError(err)
+	r.NoError(c.SetWithTags(ctx, "product:1", "1", 0, nil, "products"))
+	// product:1 is evicted, its tag must not follow the key when it is set again without tags
+	r.NoError(c.Set(ctx, "product:2", "2", 0, nil))
+	r.NoError(c.Set(ctx, "product:1", "1", 0, nil))
+
+	r.NoError(c.InvalidateTags(ctx, "products"))
+	var value string
+	r.NoError(c.Get(ctx, "product:1", &value, nil))
+	r.Equal("1", value)
+}
@@ -4,8 +4,55 @@ import (
 	"context"
 	"sync"
 	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/saigontechnology/go-shared-packages/must"
+	"github.com/saigontechnology/go-shared-packages/prometheus"
 )
 
+// ErrCacheMiss is returned when a key does not exist. It is the same value as redis.Nil
+// so existing checks against redis.Nil keep working.
+var ErrCacheMiss = redis.Nil
+
+// Entry is a key and its value used by batch operations.
+type Entry struct {
+	Key    string
+	Data   interface{}
+	Expire time.Duration
+}
+
+// NoExpiration is returned by TTL for a key which does not expire.
+const NoExpiration time.Duration = -1
+
+// Hash holds the fields of a hash as they are stored, Scan decodes a field the same way as HGet.
+type Hash map[string][]byte
+
+// Scan returns ErrCacheMiss when the field does not exist.
+func (h Hash) Scan(field string, data interface{}) error {
+	value, ok := h[field]
+	if !ok {
+		return ErrCacheMiss
+	}
+	return scanValue(value, data)
+}
+
+// ZMember is a member of a sorted set and its score.
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// ZRangeBy selects members of a sorted set by score.
+type ZRangeBy struct {
+	// Min and Max are scores, -inf or +inf. A score prefixed with ( is excluded from the range.
+	Min, Max string
+	// Count 0 returns all members after Offset
+	Offset, Count int64
+	// Reverse returns members from the highest score to the lowest, as leaderboards do
+	Reverse bool
+}
+
 type Cache interface {
 	Get(ctx context.Context, key string, data interface{}, tag *string) error
 	Set(ctx context.Context, key string, data interface{}, expire time.Duration, tag *string) error
@@ -13,7 +60,49 @@ type Cache interface {
 	HGet(ctx context.Context, key, field string, data interface{}) error
 	HSet(ctx context.Context, key, field string, data interface{}, expire time.Duration) error
 	DelKeysWithPattern(ctx context.Context, pattern string) error
+	// UnlinkKeysWithPattern deletes keys matching pattern without blocking Redis and returns how many
+	// were deleted. It goes on when a chunk of keys can not be deleted and returns the errors of all chunks.
+	UnlinkKeysWithPattern(ctx context.Context, pattern string) (int64, error)
+	// UnlinkKeysWithPatternAsync runs UnlinkKeysWithPattern in the background, it stops when ctx is done.
+	UnlinkKeysWithPatternAsync(ctx context.Context, pattern string) *Deletion
 	Del(ctx context.Context, key string) error
+	// SetWithTags works like Set and associates key with invalidationTags, so it can be deleted by InvalidateTags.
+	SetWithTags(
+		ctx context.Context,
+		key string,
+		data interface{},
+		expire time.Duration,
+		tag *string,
+		invalidationTags ...string,
+	) error
+	// InvalidateTags deletes all keys associated with the given tags without scanning the keyspace.
+	InvalidateTags(ctx context.Context, invalidationTags ...string) error
+	// MGet scans the value of keys[i] into data[i] and reports whether keys[i] was found.
+	MGet(ctx context.Context, keys []string, data []interface{}, tag *string) ([]bool, error)
+	MSet(ctx context.Context, entries []Entry, tag *string) error
+	MDel(ctx context.Context, keys ...string) error
+	// HGetAll returns an empty Hash when the key does not exist.
+	HGetAll(ctx context.Context, key string) (Hash, error)
+	HMSet(ctx context.Context, key string, values map[string]interface{}, expire time.Duration) error
+	// HDel deletes fields of a hash, the hash is deleted with its last field.
+	HDel(ctx context.Context, key string, fields ...string) error
+	// Incr, IncrBy and Decr create the counter when it does not exist. expire is only applied to a counter
+	// without expiration, so a counter created with a TTL expires at a fixed time whatever its increments.
+	Incr(ctx context.Context, key string, expire time.Duration) (int64, error)
+	IncrBy(ctx context.Context, key string, value int64, expire time.Duration) (int64, error)
+	Decr(ctx context.Context, key string, expire time.Duration) (int64, error)
+	// Expire returns ErrCacheMiss when the key does not exist, an expire of 0 removes the expiration.
+	Expire(ctx context.Context, key string, expire time.Duration) error
+	// TTL returns ErrCacheMiss when the key does not exist and NoExpiration when it does not expire.
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// ZAdd adds members to a sorted set or updates their score, expire is applied when it is positive.
+	ZAdd(ctx context.Context, key string, expire time.Duration, members ...ZMember) error
+	ZRangeByScore(ctx context.Context, key string, by ZRangeBy) ([]ZMember, error)
+	ZRem(ctx context.Context, key string, members ...string) error
+	// BumpVersion makes all keys of a versioned group unreachable at once, they expire on their own.
+	// The group of a key is its prefix before the first ':', groups are configured by CACHE_VERSIONED_GROUPS.
+	// Other instances see the new version after CACHE_VERSION_LOCAL_TTL at most.
+	BumpVersion(ctx context.Context, group string) error
 	// More functions will be added later on demand
 }
 
@@ -25,26 +114,139 @@ var (
 type Provider interface {
 	RedisCache() Cache
 	DumpCache() Cache
+	// MemoryCache is an in-process cache, it is not shared between instances of a service.
+	MemoryCache() Cache
+	// TieredCache keeps hot keys in process in front of RedisCache,
+	// local copies are invalidated on all instances through Redis pub/sub.
+	// It calls Redis directly, the circuit breaker of RedisCache does not apply to it.
+	TieredCache() Cache
+	// Locker uses the RedisCache connection and namespace. It calls Redis directly, like TieredCache.
+	Locker() Locker
+	// RedisClient is the connection used by RedisCache, it lets other packages share the cache configuration.
+	RedisClient() redis.UniversalClient
+	// HealthCheck pings Redis, it returns ErrCircuitOpen without calling Redis while the circuit breaker is open.
+	HealthCheck(ctx context.Context) error
+	// Ping pings Redis and returns its latency, it fails when Redis does not answer within timeout.
+	// Unlike HealthCheck, Redis is called even while the circuit breaker is open.
+	Ping(ctx context.Context, timeout time.Duration) (time.Duration, error)
+	// PoolStats returns the statistics of the connection pool of RedisClient.
+	PoolStats() *redis.PoolStats
+	// KeyCounts counts the keys of each namespace, the namespace of RedisCache is counted when none is given.
+	// It scans the whole keyspace, so it should not be called on every request. Keys are matched by the
+	// "<namespace>_" prefix, a key of "app_v2" is not counted for "app" when both namespaces are given.
+	KeyCounts(ctx context.Context, namespaces ...string) (map[string]int64, error)
 }
 
 type provider struct {
-	redis Cache
+	cfg        *Config
+	redis      *redisCache
+	breaker    *breakerCache
+	memory     Cache
+	tieredOnce sync.Once
+	tiered     Cache
 }
 
+// GetProvider singleton implementation makes sure only one Provider is created to avoid duplicated Redis connection pools.
+// It is configured from the environment, use New for other configurations.
 func GetProvider() Provider {
 	once.Do(func() {
-		instance = &provider{
-			redis: newRedisCache(),
-		}
+		var err error
+		instance, err = newProvider()
+		must.NotFail(err)
+		prometheus.RegisterCacheStatsCollector(newStatsReader(instance.redis, instance.cfg))
 	})
 
 	return instance
 }
 
+// New creates a Provider with its own connection pool. Options are applied in order to the configuration
+// given by WithConfig, the environment is only read without WithConfig.
+func New(options ...Option) (Provider, error) {
+	return newProvider(options...)
+}
+
+func newProvider(options ...Option) (*provider, error) {
+	cfg, err := newConfigFromOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newRedisCache(cfg)
+	if err != nil {
+		return nil, err
+	}
+	memory, err := newMemoryCache(cfg)
+	if err != nil {
+		return nil, err
+	}
+	p := &provider{
+		cfg:    cfg,
+		redis:  r,
+		memory: memory,
+	}
+	if cfg.BreakerEnabled {
+		p.breaker = newBreakerCache(p.redis, cfg.BreakerThreshold, cfg.BreakerOpenTimeout)
+	}
+	return p, nil
+}
+
 func (p *provider) DumpCache() Cache {
 	return &dumpCache{}
 }
 
 func (p *provider) RedisCache() Cache {
+	if p.breaker != nil {
+		return p.breaker
+	}
 	return p.redis
 }
+
+func (p *provider) MemoryCache() Cache {
+	return p.memory
+}
+
+func (p *provider) TieredCache() Cache {
+	p.tieredOnce.Do(func() {
+		tiered, err := newTieredCache(p.redis, p.cfg)
+		must.NotFail(err)
+		p.tiered = tiered
+	})
+	return p.tiered
+}
+
+func (p *provider) Locker() Locker {
+	return newRedisLocker(p.redis)
+}
+
+func (p *provider) RedisClient() redis.UniversalClient {
+	return p.redis.client
+}
+
+func (p *provider) HealthCheck(ctx context.Context) error {
+	ping := func() error {
+		return p.redis.client.Ping(ctx).Err()
+	}
+	if p.breaker == nil {
+		return ping()
+	}
+	called, err := p.breaker.call(ctx, ping)
+	if !called {
+		return ErrCircuitOpen
+	}
+	return err
+}
+
+func (p *provider) Ping(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	return p.redis.ping(ctx, timeout)
+}
+
+func (p *provider) PoolStats() *redis.PoolStats {
+	return p.redis.client.PoolStats()
+}
+
+func (p *provider) KeyCounts(ctx context.Context, namespaces ...string) (map[string]int64, error) {
+	if len(namespaces) == 0 {
+		namespaces = []string{p.redis.namespace}
+	}
+	return p.redis.keyCounts(ctx, namespaces)
+}
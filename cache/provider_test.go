@@ -0,0 +1,152 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/saigontechnology/go-shared-packages/cache"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+
+	first, err := cache.New(cache.WithHost(mr.Addr()), cache.WithNamespace("first"))
+	r.NoError(err)
+	second, err := cache.New(cache.WithHost(mr.Addr()), cache.WithNamespace("second"))
+	r.NoError(err)
+	r.NoError(first.HealthCheck(ctx))
+
+	r.NoError(first.RedisCache().Set(ctx, "key", "value", 0, nil))
+	var value string
+	r.NoError(first.RedisCache().Get(ctx, "key", &value, nil))
+	r.Equal("value", value)
+	r.ErrorIs(second.RedisCache().Get(ctx, "key", &value, nil), cache.ErrCacheMiss)
+	r.True(mr.Exists("first_key"))
+}
+
+func TestNew_Config(t *testing.T) {
+	t.Parallel()
+	valid := cache.Config{
+		Mode:                 cache.ModeSingle,
+		Codec:                cache.CodecJSON,
+		Compression:          cache.CompressionNone,
+		MemoryEvictionPolicy: string(cache.EvictionPolicyLRU),
+	}
+	withEvictionPolicy := func(policy string) cache.Config {
+		cfg := valid
+		cfg.MemoryEvictionPolicy = policy
+		return cfg
+	}
+
+	testCases := []struct {
+		name    string
+		options []cache.Option
+		wantErr bool
+	}{
+		{
+			name:    "Valid config",
+			options: []cache.Option{cache.WithConfig(valid)},
+		},
+		{
+			name:    "Unsupported mode",
+			options: []cache.Option{cache.WithConfig(cache.Config{Mode: "unknown"})},
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported codec",
+			options: []cache.Option{cache.WithCodec("xml", cache.CompressionNone)},
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported eviction policy",
+			options: []cache.Option{cache.WithConfig(withEvictionPolicy("random"))},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			p, err := cache.New(tc.options...)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, p.RedisCache())
+		})
+	}
+}
+
+func TestNew_ConfigSkipsEnvironment(t *testing.T) {
+	r := require.New(t)
+	t.Setenv("CACHE_SCAN_COUNT", "not a number")
+	_, err := cache.New()
+	r.Error(err)
+
+	p, err := cache.New(cache.WithConfig(cache.Config{Host: "localhost:6379"}), cache.WithNamespace("configured"))
+	r.NoError(err)
+	r.NotNil(p.RedisCache())
+}
+
+func TestNew_PartialConfig(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+
+	// Mode, Codec, Compression and the eviction policy are not given, they get their defaults
+	p, err := cache.New(cache.WithConfig(cache.Config{Host: mr.Addr(), Namespace: "a"}))
+	r.NoError(err)
+	r.NoError(p.RedisCache().Set(ctx, "key", map[string]int{"value": 1}, 0, nil))
+	var value map[string]int
+	r.NoError(p.RedisCache().Get(ctx, "key", &value, nil))
+	r.Equal(map[string]int{"value": 1}, value)
+	r.True(mr.Exists("a_key"))
+	r.NoError(p.MemoryCache().Set(ctx, "key", "value", 0, nil))
+}
+
+func TestProvider_Stats(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	p, err := cache.New(cache.WithHost(mr.Addr()), cache.WithNamespace("first"))
+	r.NoError(err)
+
+	r.NoError(mr.Set("first_a", "1"))
+	r.NoError(mr.Set("first_b", "1"))
+	r.NoError(mr.Set("second_a", "1"))
+	r.NoError(mr.Set("other", "1"))
+
+	latency, err := p.Ping(ctx, time.Second)
+	r.NoError(err)
+	r.Positive(latency)
+	r.Positive(p.PoolStats().TotalConns)
+
+	counts, err := p.KeyCounts(ctx)
+	r.NoError(err)
+	r.Equal(map[string]int64{"first": 2}, counts)
+	counts, err = p.KeyCounts(ctx, "first", "second", "third")
+	r.NoError(err)
+	r.Equal(map[string]int64{"first": 2, "second": 1, "third": 0}, counts)
+
+	// A key of a longer namespace is only counted for it
+	r.NoError(mr.Set("first_v2_a", "1"))
+	r.NoError(mr.Set("first_v2_next_a", "1"))
+	counts, err = p.KeyCounts(ctx, "first", "first_v2", "first_v2_next")
+	r.NoError(err)
+	r.Equal(map[string]int64{"first": 2, "first_v2": 1, "first_v2_next": 1}, counts)
+
+	mr.Close()
+	_, err = p.Ping(ctx, time.Second)
+	r.Error(err)
+}
@@ -3,8 +3,11 @@ package cache
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -13,21 +16,70 @@ import (
 	"github.com/redis/go-redis/v9"
 
 	"github.com/saigontechnology/go-shared-packages/list"
-	"github.com/saigontechnology/go-shared-packages/must"
 	"github.com/saigontechnology/go-shared-packages/prometheus"
 )
 
+// addTagMemberScript adds a key to a tag set. The set lives as long as its longest-living key,
+// it never expires while it holds a key without expiration.
+var addTagMemberScript = redis.NewScript(`
+redis.call("SADD", KEYS[1], ARGV[1])
+local ttl = tonumber(ARGV[2])
+if ttl <= 0 then
+	return redis.call("PERSIST", KEYS[1])
+end
+local current = redis.call("PTTL", KEYS[1])
+if current == -1 and redis.call("SCARD", KEYS[1]) > 1 then
+	return 0
+end
+if current < ttl then
+	return redis.call("PEXPIRE", KEYS[1], ttl)
+end
+return 0
+`)
+
+// incrScript increments a counter and sets its expiration when it has none.
+var incrScript = redis.NewScript(`
+local value = redis.call("INCRBY", KEYS[1], ARGV[1])
+local ttl = tonumber(ARGV[2])
+if ttl > 0 and redis.call("PTTL", KEYS[1]) == -1 then
+	redis.call("PEXPIRE", KEYS[1], ttl)
+end
+return value
+`)
+
+const tagKeyPrefix = "tag:"
+
 type redisCache struct {
-	client    *redis.Client
+	client    redis.UniversalClient
 	metric    prometheus.CacheMetric
 	namespace string
 	scanCount int64
+	codec     *valueCodec
+	versions  *groupVersions
 }
 
-func newRedisCache() *redisCache {
-	cfg, err := newConfig()
-	must.NotFail(err)
+func newRedisCache(cfg *Config) (*redisCache, error) {
+	client, err := newRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
 
+	codec, err := newValueCodec(cfg.Codec, cfg.Compression, cfg.CompressionThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	return &redisCache{
+		client:    client,
+		metric:    prometheus.GetCacheMetric(),
+		namespace: cfg.Namespace,
+		scanCount: int64(cfg.ScanCount),
+		codec:     codec,
+		versions:  newGroupVersions(cfg.VersionedGroups, cfg.VersionLocalTTL),
+	}, nil
+}
+
+func newRedisClient(cfg *Config) (redis.UniversalClient, error) {
 	var tlsConfig *tls.Config
 	if cfg.TLSEnabled {
 		tlsConfig = &tls.Config{
@@ -35,27 +87,68 @@ func newRedisCache() *redisCache {
 		}
 	}
 
-	return &redisCache{
-		client: redis.NewClient(&redis.Options{
+	addresses := cfg.Addresses
+	if len(addresses) == 0 {
+		addresses = []string{cfg.Host}
+	}
+
+	switch cfg.Mode {
+	case ModeSingle:
+		return redis.NewClient(&redis.Options{
 			Addr:      cfg.Host,
 			DB:        cfg.Database,
 			Password:  cfg.Password,
 			TLSConfig: tlsConfig,
-		}),
-		metric:    prometheus.GetCacheMetric(),
-		namespace: cfg.Namespace,
-		scanCount: int64(cfg.ScanCount),
+		}), nil
+	case ModeSentinel:
+		options := &redis.FailoverOptions{
+			MasterName:       cfg.MasterName,
+			SentinelAddrs:    addresses,
+			SentinelPassword: cfg.SentinelPassword,
+			DB:               cfg.Database,
+			Password:         cfg.Password,
+			TLSConfig:        tlsConfig,
+		}
+		if cfg.ReadFromReplica {
+			// Read-only commands are sent to replicas, writes still go to the master
+			if cfg.Database != 0 {
+				return nil, errors.New("[Cache] database can not be selected when reading from replicas in sentinel mode")
+			}
+			options.RouteRandomly = true
+			return redis.NewFailoverClusterClient(options), nil
+		}
+		return redis.NewFailoverClient(options), nil
+	case ModeCluster:
+		if cfg.Database != 0 {
+			return nil, errors.New("[Cache] database can not be selected in cluster mode")
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:         addresses,
+			Password:      cfg.Password,
+			TLSConfig:     tlsConfig,
+			ReadOnly:      cfg.ReadFromReplica,
+			RouteRandomly: cfg.ReadFromReplica,
+		}), nil
+	default:
+		return nil, fmt.Errorf("[Cache] mode %s is not supported", cfg.Mode)
 	}
 }
 
 func NewMiniRedisForTest(t *testing.T) Cache {
 	t.Helper()
 
-	mr := miniredis.RunT(t)
+	return newMiniRedisForTest(t, miniredis.RunT(t))
+}
+
+func newMiniRedisForTest(t *testing.T, mr *miniredis.Miniredis) *redisCache {
+	t.Helper()
+
 	return &redisCache{
 		namespace: "test",
 		metric:    prometheus.GetCacheMetric(),
 		client:    redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		codec:     defaultValueCodec(),
+		versions:  newGroupVersions(nil, 0),
 	}
 }
 
@@ -77,7 +170,14 @@ func (r *redisCache) Get(ctx context.Context, key string, data interface{}, tag
 
 	txn := r.metric.NewCacheGetLatencyTransaction(tag)
 	defer txn.End()
-	err := r.client.Get(ctx, r.makeAppCacheKey(key)).Scan(data)
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	value, err := r.client.Get(ctx, appKey).Bytes()
+	if err == nil {
+		err = scanValue(value, data)
+	}
 	if err == nil {
 		r.metric.CountCacheHit(tag)
 	} else {
@@ -86,6 +186,32 @@ func (r *redisCache) Get(ctx context.Context, key string, data interface{}, tag
 	return err
 }
 
+// getBytesWithTTL also returns the remaining time to live of key as replied by PTTL,
+// which is NoExpiration when the key does not expire.
+func (r *redisCache) getBytesWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	s := r.newrelicRedisSegment(ctx, "Get")
+	defer s.End()
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	var get *redis.StringCmd
+	var pttl *redis.DurationCmd
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, appKey)
+		pttl = pipe.PTTL(ctx, appKey)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, 0, err
+	}
+	value, err := get.Bytes()
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, pttl.Val(), nil
+}
+
 func (r *redisCache) Set(
 	ctx context.Context,
 	key string,
@@ -97,7 +223,15 @@ func (r *redisCache) Set(
 	defer s.End()
 	txn := r.metric.NewCacheSetLatencyTransaction(tag)
 	defer txn.End()
-	err := r.client.Set(ctx, r.makeAppCacheKey(key), data, expire).Err()
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	value, err := r.codec.encode(data)
+	if err != nil {
+		return err
+	}
+	err = r.client.Set(ctx, appKey, value, expire).Err()
 	if err != nil {
 		log.Printf("[Cache] could not set for key %s. Error: %s", key, err.Error())
 	}
@@ -107,8 +241,15 @@ func (r *redisCache) Set(
 func (r *redisCache) HGet(ctx context.Context, key, field string, data interface{}) error {
 	s := r.newrelicRedisSegment(ctx, "HGet")
 	defer s.End()
-	err := r.client.HGet(ctx, r.makeAppCacheKey(key), field).Scan(data)
-	return err
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	value, err := r.client.HGet(ctx, appKey, field).Bytes()
+	if err != nil {
+		return err
+	}
+	return scanValue(value, data)
 }
 
 func (r *redisCache) HSet(
@@ -119,12 +260,20 @@ func (r *redisCache) HSet(
 ) error {
 	s := r.newrelicRedisSegment(ctx, "HSet")
 	defer s.End()
-	err := r.client.HSet(ctx, r.makeAppCacheKey(key), field, data).Err()
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	value, err := r.codec.encode(data)
+	if err != nil {
+		return err
+	}
+	err = r.client.HSet(ctx, appKey, field, value).Err()
 	if err != nil {
 		log.Printf("[Cache] could not set for key %s. Error: %s", key, err.Error())
 	}
 
-	err = r.client.Expire(ctx, r.makeAppCacheKey(key), expire).Err()
+	err = r.client.Expire(ctx, appKey, expire).Err()
 	if err != nil {
 		log.Printf("[Cache] could not set expire key %s. Error: %s", key, err.Error())
 	}
@@ -135,13 +284,17 @@ func (r *redisCache) HSet(
 func (r *redisCache) RemoveHashKey(ctx context.Context, key string) error {
 	s := r.newrelicRedisSegment(ctx, "RemoveHashKey")
 	defer s.End()
-	fieldKeys, err := r.client.HKeys(ctx, r.makeAppCacheKey(key)).Result()
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	fieldKeys, err := r.client.HKeys(ctx, appKey).Result()
 	if err != nil {
 		log.Printf("[Cache] delete key %s. Error: %s", key, err.Error())
 	}
 
 	if len(fieldKeys) > 0 {
-		err = r.client.HDel(ctx, r.makeAppCacheKey(key), fieldKeys...).Err()
+		err = r.client.HDel(ctx, appKey, fieldKeys...).Err()
 
 		if err != nil {
 			log.Printf("[Cache] delete key %s. Error: %s", key, err.Error())
@@ -152,21 +305,93 @@ func (r *redisCache) RemoveHashKey(ctx context.Context, key string) error {
 }
 
 func (r *redisCache) DelKeysWithPattern(ctx context.Context, pattern string) error {
-	// add namespace to pattern
-	pattern = r.makeAppCacheKey(pattern)
+	_, err := r.UnlinkKeysWithPattern(ctx, pattern)
+	return err
+}
+
+func (r *redisCache) UnlinkKeysWithPattern(ctx context.Context, pattern string) (int64, error) {
+	var deleted atomic.Int64
+	err := r.unlinkKeysWithPattern(ctx, pattern, func(n int64) {
+		deleted.Add(n)
+	})
+	return deleted.Load(), err
+}
+
+func (r *redisCache) UnlinkKeysWithPatternAsync(ctx context.Context, pattern string) *Deletion {
+	return runDeletion(ctx, func(ctx context.Context, progress func(deleted int64)) error {
+		return r.unlinkKeysWithPattern(ctx, pattern, progress)
+	})
+}
+
+// unlinkKeysWithPattern reports the number of keys deleted by each chunk to progress.
+func (r *redisCache) unlinkKeysWithPattern(ctx context.Context, pattern string, progress func(deleted int64)) error {
+	s := r.newrelicRedisSegment(ctx, "UnlinkKeysWithPattern")
+	defer s.End()
+	// add namespace to pattern, and the versions to the pattern of a versioned group
+	match, matchesKey := r.versionedPattern(pattern)
+	if match == "" {
+		match = r.namespacedKey(pattern)
+	}
+	// Each master of a cluster only scans its own keys
+	if cluster, ok := r.client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, master *redis.Client) error {
+			return r.unlinkScannedKeys(ctx, master, match, matchesKey, progress)
+		})
+	}
+	return r.unlinkScannedKeys(ctx, r.client, match, matchesKey, progress)
+}
+
+// versionedPattern rewrites the pattern group:rest of a versioned group to the SCAN pattern of its keys
+// in all versions. The version may match more than its own segment, like "v1:a" for "v*", so the scanned keys
+// are filtered by matchesKey. It returns an empty pattern when the group of pattern is not versioned.
+func (r *redisCache) versionedPattern(pattern string) (string, func(key string) bool) {
+	group, rest, found := strings.Cut(pattern, ":")
+	if !found || !r.versions.versioned(group) {
+		return "", nil
+	}
+	prefix := fmt.Sprintf("%s_%s:v", r.namespace, group)
+	matchesKey := func(key string) bool {
+		_, keyRest, found := strings.Cut(strings.TrimPrefix(key, prefix), ":")
+		return found && matchPattern(rest, keyRest)
+	}
+	return prefix + "*:" + rest, matchesKey
+}
+
+// unlinkScannedKeys keeps scanning when a chunk can not be deleted, errors of all chunks are returned together.
+// Only the scanned keys accepted by matchesKey are deleted, all of them when it is nil.
+func (r *redisCache) unlinkScannedKeys(
+	ctx context.Context,
+	scanner redis.Cmdable,
+	pattern string,
+	matchesKey func(key string) bool,
+	progress func(deleted int64),
+) error {
+	var errs []error
 	cursor := uint64(0)
 	for {
 		var keys []string
 		var err error
-		keys, cursor, err = r.client.Scan(ctx, cursor, pattern, r.scanCount).Result()
+		keys, cursor, err = scanner.Scan(ctx, cursor, pattern, r.scanCount).Result()
 		if err != nil {
-			return err
+			return errors.Join(append(errs, err)...)
+		}
+		if matchesKey != nil {
+			matched := keys[:0]
+			for _, key := range keys {
+				if matchesKey(key) {
+					matched = append(matched, key)
+				}
+			}
+			keys = matched
 		}
 
 		keyChunks := list.Chunk(keys, 1024)
 		for _, keysInChunk := range keyChunks {
-			//nolint: errcheck
-			r.client.Del(ctx, keysInChunk...)
+			deleted, err := r.unlinkKeys(ctx, keysInChunk)
+			progress(deleted)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("[Cache] could not delete %d keys: %w", len(keysInChunk), err))
+			}
 		}
 
 		if cursor == 0 {
@@ -174,13 +399,475 @@ func (r *redisCache) DelKeysWithPattern(ctx context.Context, pattern string) err
 			break
 		}
 	}
-	return nil
+	return errors.Join(errs...)
+}
+
+// unlinkKeys deletes namespaced keys without blocking Redis and returns how many existed.
+// A cluster can not delete keys of different slots in one command, so they are deleted one by one in a pipeline.
+func (r *redisCache) unlinkKeys(ctx context.Context, keys []string) (int64, error) {
+	if _, ok := r.client.(*redis.ClusterClient); !ok {
+		return r.client.Unlink(ctx, keys...).Result()
+	}
+	cmds := make([]*redis.IntCmd, len(keys))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Unlink(ctx, key)
+		}
+		return nil
+	})
+	var deleted int64
+	for _, cmd := range cmds {
+		deleted += cmd.Val()
+	}
+	return deleted, err
 }
 
 func (r *redisCache) Del(ctx context.Context, key string) error {
-	return r.client.Del(ctx, r.makeAppCacheKey(key)).Err()
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	return r.client.Del(ctx, appKey).Err()
+}
+
+func (r *redisCache) SetWithTags(
+	ctx context.Context,
+	key string,
+	data interface{},
+	expire time.Duration,
+	tag *string,
+	invalidationTags ...string,
+) error {
+	s := r.newrelicRedisSegment(ctx, "SetWithTags")
+	defer s.End()
+	txn := r.metric.NewCacheSetLatencyTransaction(tag)
+	defer txn.End()
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	value, err := r.codec.encode(data)
+	if err != nil {
+		return err
+	}
+	// Tags are added before the value, so a stored value can always be invalidated
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, invalidationTag := range invalidationTags {
+			// Scripts are not loaded in pipelines, so EVALSHA fallback is not possible
+			addTagMemberScript.Eval(
+				ctx,
+				pipe,
+				[]string{r.internalKey(tagKeyPrefix + invalidationTag)},
+				key,
+				expire.Milliseconds(),
+			)
+		}
+		pipe.Set(ctx, appKey, value, expire)
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Cache] could not set for key %s with tags. Error: %s", key, err.Error())
+	}
+	return err
+}
+
+func (r *redisCache) InvalidateTags(ctx context.Context, invalidationTags ...string) error {
+	_, err := r.invalidateTags(ctx, invalidationTags...)
+	return err
+}
+
+// invalidateTags returns the keys associated with the tags, they are deleted even when an error is returned.
+func (r *redisCache) invalidateTags(ctx context.Context, invalidationTags ...string) ([]string, error) {
+	s := r.newrelicRedisSegment(ctx, "InvalidateTags")
+	defer s.End()
+	var invalidatedKeys []string
+	for _, invalidationTag := range invalidationTags {
+		tagKey := r.internalKey(tagKeyPrefix + invalidationTag)
+		keys, err := r.client.SMembers(ctx, tagKey).Result()
+		if err != nil {
+			return invalidatedKeys, err
+		}
+
+		for _, keysInChunk := range list.Chunk(keys, 1024) {
+			appCacheKeys, err := r.makeAppCacheKeys(ctx, keysInChunk)
+			if err != nil {
+				return invalidatedKeys, err
+			}
+			if _, err := r.unlinkKeys(ctx, appCacheKeys); err != nil {
+				return invalidatedKeys, err
+			}
+			invalidatedKeys = append(invalidatedKeys, keysInChunk...)
+
+			// Only remove invalidated keys, keys tagged in the meantime stay in the tag
+			members := make([]interface{}, len(keysInChunk))
+			for i, key := range keysInChunk {
+				members[i] = key
+			}
+			if err := r.client.SRem(ctx, tagKey, members...).Err(); err != nil {
+				return invalidatedKeys, err
+			}
+		}
+	}
+	return invalidatedKeys, nil
+}
+
+func (r *redisCache) MGet(
+	ctx context.Context,
+	keys []string,
+	data []interface{},
+	tag *string,
+) ([]bool, error) {
+	if len(keys) != len(data) {
+		return nil, fmt.Errorf("[Cache] MGet got %d keys but %d destinations", len(keys), len(data))
+	}
+	txn := r.metric.NewCacheGetLatencyTransaction(tag)
+	defer txn.End()
+	values, _, err := r.getBytesMany(ctx, keys, false)
+	if err != nil {
+		return nil, err
+	}
+	return scanValues(values, data, tag, r.metric)
+}
+
+// getBytesMany reads keys in one pipeline, the value of a missing key is nil.
+// The remaining time to live of each key is only read when withTTL is set.
+func (r *redisCache) getBytesMany(ctx context.Context, keys []string, withTTL bool) ([][]byte, []time.Duration, error) {
+	s := r.newrelicRedisSegment(ctx, "MGet")
+	defer s.End()
+	appKeys, err := r.makeAppCacheKeys(ctx, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	// A pipeline of GET works in cluster mode where MGET of keys in different slots fails
+	cmds := make([]*redis.StringCmd, len(keys))
+	pttls := make([]*redis.DurationCmd, len(keys))
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, appKey := range appKeys {
+			cmds[i] = pipe.Get(ctx, appKey)
+			if withTTL {
+				pttls[i] = pipe.PTTL(ctx, appKey)
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, nil, err
+	}
+
+	values := make([][]byte, len(keys))
+	var ttls []time.Duration
+	if withTTL {
+		ttls = make([]time.Duration, len(keys))
+	}
+	for i, cmd := range cmds {
+		value, err := cmd.Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		values[i] = value
+		if withTTL {
+			ttls[i] = pttls[i].Val()
+		}
+	}
+	return values, ttls, nil
+}
+
+func (r *redisCache) MSet(ctx context.Context, entries []Entry, tag *string) error {
+	s := r.newrelicRedisSegment(ctx, "MSet")
+	defer s.End()
+	txn := r.metric.NewCacheSetLatencyTransaction(tag)
+	defer txn.End()
+	appKeys := make([]string, len(entries))
+	values := make([][]byte, len(entries))
+	for i, entry := range entries {
+		appKey, err := r.makeAppCacheKey(ctx, entry.Key)
+		if err != nil {
+			return err
+		}
+		value, err := r.codec.encode(entry.Data)
+		if err != nil {
+			return err
+		}
+		appKeys[i] = appKey
+		values[i] = value
+	}
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, entry := range entries {
+			pipe.Set(ctx, appKeys[i], values[i], entry.Expire)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Cache] could not set %d keys. Error: %s", len(entries), err.Error())
+	}
+	return err
 }
 
-func (r *redisCache) makeAppCacheKey(key string) string {
+func (r *redisCache) MDel(ctx context.Context, keys ...string) error {
+	s := r.newrelicRedisSegment(ctx, "MDel")
+	defer s.End()
+	appKeys, err := r.makeAppCacheKeys(ctx, keys)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, appKey := range appKeys {
+			pipe.Del(ctx, appKey)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *redisCache) HGetAll(ctx context.Context, key string) (Hash, error) {
+	s := r.newrelicRedisSegment(ctx, "HGetAll")
+	defer s.End()
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	values, err := r.client.HGetAll(ctx, appKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	hash := make(Hash, len(values))
+	for field, value := range values {
+		hash[field] = []byte(value)
+	}
+	return hash, nil
+}
+
+func (r *redisCache) HMSet(
+	ctx context.Context,
+	key string,
+	values map[string]interface{},
+	expire time.Duration,
+) error {
+	s := r.newrelicRedisSegment(ctx, "HMSet")
+	defer s.End()
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	fields := make([]interface{}, 0, 2*len(values))
+	for field, data := range values {
+		value, err := r.codec.encode(data)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, field, value)
+	}
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, appKey, fields...)
+		if expire > 0 {
+			pipe.PExpire(ctx, appKey, expire)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Cache] could not set fields of key %s. Error: %s", key, err.Error())
+	}
+	return err
+}
+
+func (r *redisCache) HDel(ctx context.Context, key string, fields ...string) error {
+	s := r.newrelicRedisSegment(ctx, "HDel")
+	defer s.End()
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	return r.client.HDel(ctx, appKey, fields...).Err()
+}
+
+func (r *redisCache) Incr(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	return r.IncrBy(ctx, key, 1, expire)
+}
+
+func (r *redisCache) IncrBy(ctx context.Context, key string, value int64, expire time.Duration) (int64, error) {
+	s := r.newrelicRedisSegment(ctx, "IncrBy")
+	defer s.End()
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	return incrScript.Run(ctx, r.client, []string{appKey}, value, expire.Milliseconds()).Int64()
+}
+
+func (r *redisCache) Decr(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	return r.IncrBy(ctx, key, -1, expire)
+}
+
+func (r *redisCache) Expire(ctx context.Context, key string, expire time.Duration) error {
+	s := r.newrelicRedisSegment(ctx, "Expire")
+	defer s.End()
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	var exists bool
+	if expire > 0 {
+		exists, err = r.client.PExpire(ctx, appKey, expire).Result()
+	} else {
+		// PERSIST does not tell a missing key from a key without expiration
+		var n int64
+		n, err = r.client.Exists(ctx, appKey).Result()
+		if err == nil && n > 0 {
+			exists = true
+			err = r.client.Persist(ctx, appKey).Err()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCacheMiss
+	}
+	return nil
+}
+
+func (r *redisCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	s := r.newrelicRedisSegment(ctx, "TTL")
+	defer s.End()
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	ttl, err := r.client.PTTL(ctx, appKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	// go-redis returns the -2 and -1 replies as they are
+	switch ttl {
+	case -2:
+		return 0, ErrCacheMiss
+	case -1:
+		return NoExpiration, nil
+	default:
+		return ttl, nil
+	}
+}
+
+func (r *redisCache) ZAdd(ctx context.Context, key string, expire time.Duration, members ...ZMember) error {
+	s := r.newrelicRedisSegment(ctx, "ZAdd")
+	defer s.End()
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	zs := make([]redis.Z, len(members))
+	for i, member := range members {
+		zs[i] = redis.Z{Score: member.Score, Member: member.Member}
+	}
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, appKey, zs...)
+		if expire > 0 {
+			pipe.PExpire(ctx, appKey, expire)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Cache] could not add members to key %s. Error: %s", key, err.Error())
+	}
+	return err
+}
+
+func (r *redisCache) ZRangeByScore(ctx context.Context, key string, by ZRangeBy) ([]ZMember, error) {
+	s := r.newrelicRedisSegment(ctx, "ZRangeByScore")
+	defer s.End()
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	opt := &redis.ZRangeBy{Min: by.Min, Max: by.Max, Offset: by.Offset, Count: by.Count}
+	if opt.Offset > 0 && opt.Count == 0 {
+		// LIMIT with a count of 0 returns nothing, a negative count returns all members
+		opt.Count = -1
+	}
+	var zs []redis.Z
+	if by.Reverse {
+		zs, err = r.client.ZRevRangeByScoreWithScores(ctx, appKey, opt).Result()
+	} else {
+		zs, err = r.client.ZRangeByScoreWithScores(ctx, appKey, opt).Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+	members := make([]ZMember, len(zs))
+	for i, z := range zs {
+		members[i] = ZMember{Member: z.Member.(string), Score: z.Score}
+	}
+	return members, nil
+}
+
+func (r *redisCache) ZRem(ctx context.Context, key string, members ...string) error {
+	s := r.newrelicRedisSegment(ctx, "ZRem")
+	defer s.End()
+	appKey, err := r.makeAppCacheKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, len(members))
+	for i, member := range members {
+		values[i] = member
+	}
+	return r.client.ZRem(ctx, appKey, values...).Err()
+}
+
+// tryLock acquires a lock which is released by the returned function.
+func (r *redisCache) tryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	lock, err := newRedisLocker(r).TryLock(ctx, key, ttl)
+	if errors.Is(err, ErrLockNotAcquired) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	unlock := func() {
+		if err := lock.Unlock(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("[Cache] could not release lock %s. Error: %s", key, err.Error())
+		}
+	}
+	return unlock, true, nil
+}
+
+// makeAppCacheKey adds the namespace to key, and the version of its group when the group is versioned.
+func (r *redisCache) makeAppCacheKey(ctx context.Context, key string) (string, error) {
+	group, rest, found := strings.Cut(key, ":")
+	if !found || !r.versions.versioned(group) {
+		return r.namespacedKey(key), nil
+	}
+	version, err := r.versions.get(ctx, r, group)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s_%s:v%d:%s", r.namespace, group, version, rest), nil
+}
+
+func (r *redisCache) makeAppCacheKeys(ctx context.Context, keys []string) ([]string, error) {
+	appKeys := make([]string, len(keys))
+	for i, key := range keys {
+		appKey, err := r.makeAppCacheKey(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		appKeys[i] = appKey
+	}
+	return appKeys, nil
+}
+
+// namespacedKey only adds the namespace, it is used for patterns.
+func (r *redisCache) namespacedKey(key string) string {
 	return fmt.Sprintf("%s_%s", r.namespace, key)
 }
+
+// internalKey is the key of data kept by the cache itself, like tag sets, locks and group versions.
+// It is separated from the namespace by ':' instead of '_', so no key of a caller produces it
+// and the patterns of DelKeysWithPattern and UnlinkKeysWithPattern do not match it.
+func (r *redisCache) internalKey(key string) string {
+	return fmt.Sprintf("%s:%s", r.namespace, key)
+}
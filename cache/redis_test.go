@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRedisClient(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name         string
+		cfg          *Config
+		expectedType redis.UniversalClient
+		expectedErr  bool
+	}{
+		{
+			name:         "Single",
+			cfg:          &Config{Mode: ModeSingle, Host: "localhost:6379"},
+			expectedType: &redis.Client{},
+		},
+		{
+			name:         "Sentinel",
+			cfg:          &Config{Mode: ModeSentinel, MasterName: "master", Addresses: []string{"s1:26379", "s2:26379"}},
+			expectedType: &redis.Client{},
+		},
+		{
+			name: "Sentinel reading from replicas",
+			cfg: &Config{
+				Mode:            ModeSentinel,
+				MasterName:      "master",
+				Addresses:       []string{"s1:26379"},
+				ReadFromReplica: true,
+			},
+			expectedType: &redis.ClusterClient{},
+		},
+		{
+			name:         "Cluster",
+			cfg:          &Config{Mode: ModeCluster, Addresses: []string{"n1:6379", "n2:6379"}},
+			expectedType: &redis.ClusterClient{},
+		},
+		{
+			name:        "Cluster with a database",
+			cfg:         &Config{Mode: ModeCluster, Addresses: []string{"n1:6379"}, Database: 1},
+			expectedErr: true,
+		},
+		{
+			name: "Sentinel reading from replicas with a database",
+			cfg: &Config{
+				Mode:            ModeSentinel,
+				MasterName:      "master",
+				Addresses:       []string{"s1:26379"},
+				ReadFromReplica: true,
+				Database:        1,
+			},
+			expectedErr: true,
+		},
+		{
+			name:        "Unknown mode",
+			cfg:         &Config{Mode: "unknown"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			client, err := newRedisClient(tc.cfg)
+			if tc.expectedErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+			r.IsType(tc.expectedType, client)
+			r.NoError(client.Close())
+		})
+	}
+}
+
+func TestRedisCache_InternalKeys(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	c := newMiniRedisForTest(t, mr)
+	c.versions = newGroupVersions([]string{"user"}, time.Minute)
+
+	// Keys of callers do not collide with the tag sets, locks and versions of the cache
+	r.NoError(c.Set(ctx, "tag:products", "value", time.Minute, nil))
+	r.NoError(c.SetWithTags(ctx, "product:1", "1", time.Minute, nil, "products"))
+	r.NoError(c.InvalidateTags(ctx, "products"))
+	var value string
+	r.NoError(c.Get(ctx, "tag:products", &value, nil))
+
+	lock, err := newRedisLocker(c).TryLock(ctx, "job", time.Minute)
+	r.NoError(err)
+	r.NoError(c.BumpVersion(ctx, "user"))
+	r.NoError(c.DelKeysWithPattern(ctx, "*"))
+	r.Equal([]string{"test:lock:job", "test:version:user"}, mr.Keys())
+	r.NoError(lock.Unlock(ctx))
+}
@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/saigontechnology/go-shared-packages/prometheus"
+)
+
+func (r *redisCache) ping(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// keyCounts counts the keys whose name starts with "<namespace>_" for the given namespaces. A key matching
+// several namespaces, like "app_v2_key" for "app" and "app_v2", is only counted for the longest one.
+func (r *redisCache) keyCounts(ctx context.Context, namespaces []string) (map[string]int64, error) {
+	s := r.newrelicRedisSegment(ctx, "KeyCounts")
+	defer s.End()
+	counts := make(map[string]int64, len(namespaces))
+	var mu sync.Mutex
+	for _, namespace := range namespaces {
+		pattern := namespace + "_*"
+		longer := longerNamespaces(namespace, namespaces)
+		count := func(ctx context.Context, scanner redis.Cmdable) error {
+			keys, err := r.countScannedKeys(ctx, scanner, pattern, longer)
+			mu.Lock()
+			counts[namespace] += keys
+			mu.Unlock()
+			return err
+		}
+		// Each master of a cluster only scans its own keys
+		var err error
+		if cluster, ok := r.client.(*redis.ClusterClient); ok {
+			err = cluster.ForEachMaster(ctx, func(ctx context.Context, master *redis.Client) error {
+				return count(ctx, master)
+			})
+		} else {
+			err = count(ctx, r.client)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
+
+// longerNamespaces returns the key prefixes of the namespaces whose keys also match the pattern of namespace.
+func longerNamespaces(namespace string, namespaces []string) []string {
+	var prefixes []string
+	for _, other := range namespaces {
+		if len(other) > len(namespace) && strings.HasPrefix(other, namespace+"_") {
+			prefixes = append(prefixes, other+"_")
+		}
+	}
+	return prefixes
+}
+
+// countScannedKeys counts the keys matching pattern, except the keys starting with one of the excluded prefixes.
+func (r *redisCache) countScannedKeys(
+	ctx context.Context,
+	scanner redis.Cmdable,
+	pattern string,
+	excluded []string,
+) (int64, error) {
+	var count int64
+	cursor := uint64(0)
+	for {
+		keys, next, err := scanner.Scan(ctx, cursor, pattern, r.scanCount).Result()
+		if err != nil {
+			return count, err
+		}
+		for _, key := range keys {
+			if !hasAnyPrefix(key, excluded) {
+				count++
+			}
+		}
+		if next == 0 {
+			return count, nil
+		}
+		cursor = next
+	}
+}
+
+// statsReader feeds the cache statistics collector of the prometheus package.
+// Counting keys scans the whole keyspace, so counts are refreshed at most once per keysInterval.
+type statsReader struct {
+	redis        *redisCache
+	pingTimeout  time.Duration
+	namespaces   []string
+	keysInterval time.Duration
+	now          func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]int64
+	countedAt time.Time
+}
+
+func newStatsReader(r *redisCache, cfg *Config) *statsReader {
+	namespaces := cfg.StatsNamespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{r.namespace}
+	}
+	return &statsReader{
+		redis:        r,
+		pingTimeout:  cfg.StatsPingTimeout,
+		namespaces:   namespaces,
+		keysInterval: cfg.StatsKeysInterval,
+		now:          time.Now,
+	}
+}
+
+func (s *statsReader) ReadCacheStats(ctx context.Context) prometheus.CacheStats {
+	pool := s.redis.client.PoolStats()
+	stats := prometheus.CacheStats{
+		Hits:       pool.Hits,
+		Misses:     pool.Misses,
+		Timeouts:   pool.Timeouts,
+		TotalConns: pool.TotalConns,
+		IdleConns:  pool.IdleConns,
+		StaleConns: pool.StaleConns,
+	}
+	latency, err := s.redis.ping(ctx, s.pingTimeout)
+	if err != nil {
+		return stats
+	}
+	stats.Up = true
+	stats.PingLatency = latency
+	stats.Keys = s.keyCounts(ctx)
+	return stats
+}
+
+// keyCounts returns the last counts when they can not be refreshed.
+func (s *statsReader) keyCounts(ctx context.Context) map[string]int64 {
+	if s.keysInterval <= 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys != nil && s.now().Sub(s.countedAt) < s.keysInterval {
+		return s.keys
+	}
+	keys, err := s.redis.keyCounts(ctx, s.namespaces)
+	if err != nil {
+		log.Printf("[Cache] could not count keys of %v. Error: %s", s.namespaces, err.Error())
+		return s.keys
+	}
+	s.keys = keys
+	s.countedAt = s.now()
+	return s.keys
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsReader_ReadCacheStats(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	reader := newStatsReader(newMiniRedisForTest(t, mr), &Config{
+		StatsPingTimeout:  time.Second,
+		StatsKeysInterval: time.Minute,
+	})
+	now := time.Now()
+	reader.now = func() time.Time { return now }
+
+	r.NoError(mr.Set("test_a", "1"))
+	stats := reader.ReadCacheStats(ctx)
+	r.True(stats.Up)
+	r.Positive(stats.PingLatency)
+	r.Equal(map[string]int64{"test": 1}, stats.Keys)
+
+	// Keys are counted again once the interval is over
+	r.NoError(mr.Set("test_b", "1"))
+	r.Equal(map[string]int64{"test": 1}, reader.ReadCacheStats(ctx).Keys)
+	now = now.Add(time.Minute)
+	r.Equal(map[string]int64{"test": 2}, reader.ReadCacheStats(ctx).Keys)
+
+	mr.Close()
+	stats = reader.ReadCacheStats(ctx)
+	r.False(stats.Up)
+	r.Nil(stats.Keys)
+}
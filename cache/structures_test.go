@@ -0,0 +1,150 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/saigontechnology/go-shared-packages/cache"
+)
+
+func TestCache_Hash(t *testing.T) {
+	t.Parallel()
+	for _, backend := range backendsForTest() {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			ctx := context.Background()
+			c := backend.newCache(t)
+
+			hash, err := c.HGetAll(ctx, "hash")
+			r.NoError(err)
+			r.Empty(hash)
+
+			r.NoError(c.HMSet(ctx, "hash", map[string]interface{}{"name": "a", "count": 2}, time.Minute))
+			hash, err = c.HGetAll(ctx, "hash")
+			r.NoError(err)
+			r.Len(hash, 2)
+			var name string
+			r.NoError(hash.Scan("name", &name))
+			r.Equal("a", name)
+			var count int
+			r.NoError(hash.Scan("count", &count))
+			r.Equal(2, count)
+			r.ErrorIs(hash.Scan("missing", &name), cache.ErrCacheMiss)
+
+			r.NoError(c.HDel(ctx, "hash", "name"))
+			r.ErrorIs(c.HGet(ctx, "hash", "name", &name), cache.ErrCacheMiss)
+			r.NoError(c.HDel(ctx, "hash", "count"))
+			_, err = c.TTL(ctx, "hash")
+			r.ErrorIs(err, cache.ErrCacheMiss)
+		})
+	}
+}
+
+func TestCache_Counter(t *testing.T) {
+	t.Parallel()
+	for _, backend := range backendsForTest() {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			ctx := context.Background()
+			c := backend.newCache(t)
+
+			n, err := c.Incr(ctx, "counter", time.Minute)
+			r.NoError(err)
+			r.Equal(int64(1), n)
+			n, err = c.IncrBy(ctx, "counter", 5, time.Hour)
+			r.NoError(err)
+			r.Equal(int64(6), n)
+			n, err = c.Decr(ctx, "counter", time.Hour)
+			r.NoError(err)
+			r.Equal(int64(5), n)
+
+			// The expiration of the first increment is kept
+			ttl, err := c.TTL(ctx, "counter")
+			r.NoError(err)
+			r.Greater(ttl, time.Duration(0))
+			r.LessOrEqual(ttl, time.Minute)
+
+			r.NoError(c.Expire(ctx, "counter", 0))
+			ttl, err = c.TTL(ctx, "counter")
+			r.NoError(err)
+			r.Equal(cache.NoExpiration, ttl)
+			r.ErrorIs(c.Expire(ctx, "missing", time.Minute), cache.ErrCacheMiss)
+
+			r.NoError(c.Set(ctx, "text", "a", time.Minute, nil))
+			_, err = c.Incr(ctx, "text", 0)
+			r.Error(err)
+		})
+	}
+}
+
+func TestCache_SortedSet(t *testing.T) {
+	t.Parallel()
+	for _, backend := range backendsForTest() {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			ctx := context.Background()
+			c := backend.newCache(t)
+
+			r.NoError(c.ZAdd(ctx, "board", time.Minute,
+				cache.ZMember{Member: "a", Score: 10},
+				cache.ZMember{Member: "b", Score: 30},
+				cache.ZMember{Member: "c", Score: 20},
+				cache.ZMember{Member: "d", Score: 20},
+			))
+
+			testCases := []struct {
+				name     string
+				by       cache.ZRangeBy
+				expected []cache.ZMember
+			}{
+				{
+					name: "All",
+					by:   cache.ZRangeBy{Min: "-inf", Max: "+inf"},
+					expected: []cache.ZMember{
+						{Member: "a", Score: 10},
+						{Member: "c", Score: 20},
+						{Member: "d", Score: 20},
+						{Member: "b", Score: 30},
+					},
+				},
+				{
+					name:     "Exclusive bounds",
+					by:       cache.ZRangeBy{Min: "(10", Max: "(30"},
+					expected: []cache.ZMember{{Member: "c", Score: 20}, {Member: "d", Score: 20}},
+				},
+				{
+					name:     "Top 2",
+					by:       cache.ZRangeBy{Min: "-inf", Max: "+inf", Count: 2, Reverse: true},
+					expected: []cache.ZMember{{Member: "b", Score: 30}, {Member: "d", Score: 20}},
+				},
+				{
+					name:     "Offset without count",
+					by:       cache.ZRangeBy{Min: "-inf", Max: "+inf", Offset: 3},
+					expected: []cache.ZMember{{Member: "b", Score: 30}},
+				},
+			}
+			for _, tc := range testCases {
+				members, err := c.ZRangeByScore(ctx, "board", tc.by)
+				r.NoError(err, tc.name)
+				r.Equal(tc.expected, members, tc.name)
+			}
+
+			r.NoError(c.ZRem(ctx, "board", "a", "b", "c"))
+			members, err := c.ZRangeByScore(ctx, "board", cache.ZRangeBy{Min: "-inf", Max: "+inf"})
+			r.NoError(err)
+			r.Equal([]cache.ZMember{{Member: "d", Score: 20}}, members)
+
+			_, err = c.ZRangeByScore(ctx, "board", cache.ZRangeBy{Min: "low", Max: "+inf"})
+			r.Error(err)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package cache_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/saigontechnology/go-shared-packages/cache"
+)
+
+func TestTyped_StaleWhileRevalidate(t *testing.T) {
+	t.Parallel()
+	for _, backend := range backendsForTest() {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			ctx := context.Background()
+			typed := cache.NewTyped[int32](
+				backend.newCache(t),
+				nil,
+				cache.WithStaleWhileRevalidate(50*time.Millisecond),
+			)
+
+			var calls atomic.Int32
+			loader := func(ctx context.Context) (int32, error) {
+				return calls.Add(1), nil
+			}
+
+			value, err := typed.GetOrLoad(ctx, "key", time.Minute, nil, loader)
+			r.NoError(err)
+			r.Equal(int32(1), value)
+			value, err = typed.GetOrLoad(ctx, "key", time.Minute, nil, loader)
+			r.NoError(err)
+			r.Equal(int32(1), value)
+
+			// The stale value is served while it is reloaded in the background
+			time.Sleep(100 * time.Millisecond)
+			value, err = typed.GetOrLoad(ctx, "key", time.Minute, nil, loader)
+			r.NoError(err)
+			r.Equal(int32(1), value)
+			r.Eventually(func() bool {
+				value, err := typed.Get(ctx, "key", nil)
+				return err == nil && value == 2
+			}, time.Second, 10*time.Millisecond)
+			r.Equal(int32(2), calls.Load())
+		})
+	}
+}
+
+func TestTyped_NegativeCaching(t *testing.T) {
+	t.Parallel()
+	for _, backend := range backendsForTest() {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			ctx := context.Background()
+			c := backend.newCache(t)
+			typed := cache.NewTyped[string](c, nil, cache.WithNegativeCaching(time.Minute))
+
+			var calls atomic.Int32
+			loader := func(ctx context.Context) (string, error) {
+				calls.Add(1)
+				return "", cache.ErrKnownAbsent
+			}
+
+			for i := 0; i < 3; i++ {
+				_, err := typed.GetOrLoad(ctx, "unknown", time.Minute, nil, loader)
+				r.ErrorIs(err, cache.ErrKnownAbsent)
+			}
+			r.Equal(int32(1), calls.Load())
+			_, err := typed.Get(ctx, "unknown", nil)
+			r.ErrorIs(err, cache.ErrKnownAbsent)
+
+			r.NoError(typed.Set(ctx, "known", "value", time.Minute, nil))
+			values, err := typed.GetMany(ctx, []string{"unknown", "known", "missing"}, nil)
+			r.NoError(err)
+			r.Equal(map[string]string{"known": "value"}, values)
+
+			_, err = typed.Get(ctx, "missing", nil)
+			r.ErrorIs(err, cache.ErrCacheMiss)
+
+			withoutNegativeCaching := cache.NewTyped[string](c, nil)
+			r.Error(withoutNegativeCaching.SetAbsent(ctx, "unknown", nil))
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/saigontechnology/go-shared-packages/cache"
+)
+
+func TestCache_InvalidateTags(t *testing.T) {
+	t.Parallel()
+	for _, tc := range backendsForTest() {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			ctx := context.Background()
+			c := tc.newCache(t)
+			r.NoError(c.SetWithTags(ctx, "product:1", "1", time.Minute, nil, "products"))
+			r.NoError(c.SetWithTags(ctx, "product:2", "2", 0, nil, "products", "category:1"))
+			r.NoError(c.SetWithTags(ctx, "category:1", "1", time.Minute, nil, "category:1"))
+			r.NoError(c.Set(ctx, "untagged", "1", time.Minute, nil))
+
+			assertExists := func(expected map[string]bool) {
+				for key, exists := range expected {
+					var value string
+					err := c.Get(ctx, key, &value, nil)
+					if exists {
+						r.NoError(err, key)
+					} else {
+						r.ErrorIs(err, cache.ErrCacheMiss, key)
+					}
+				}
+			}
+
+			r.NoError(c.InvalidateTags(ctx, "products"))
+			assertExists(map[string]bool{
+				"product:1":  false,
+				"product:2":  false,
+				"category:1": true,
+				"untagged":   true,
+			})
+
+			r.NoError(c.SetWithTags(ctx, "product:2", "2", time.Minute, nil, "products", "category:1"))
+			r.NoError(c.InvalidateTags(ctx, "category:1", "unknown"))
+			assertExists(map[string]bool{
+				"product:2":  false,
+				"category:1": false,
+				"untagged":   true,
+			})
+		})
+	}
+}
@@ -0,0 +1,394 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/saigontechnology/go-shared-packages/must"
+	"github.com/saigontechnology/go-shared-packages/prometheus"
+)
+
+const invalidationChannelSuffix = "cache_invalidation"
+
+// invalidation is broadcast to all instances when a key is changed, so they drop their local copy.
+type invalidation struct {
+	Origin  string   `json:"origin"`
+	Key     string   `json:"key,omitempty"`
+	Keys    []string `json:"keys,omitempty"`
+	Pattern string   `json:"pattern,omitempty"`
+	// Group is a versioned group whose version was bumped
+	Group string `json:"group,omitempty"`
+}
+
+// tieredCache keeps recently read values in process (near cache) in front of Redis.
+// Only plain values are cached locally, hash operations always go to Redis.
+// A local copy lives at most localTTL, which bounds staleness when an invalidation is missed,
+// and never outlives the remote key.
+type tieredCache struct {
+	local      *memoryCache
+	remote     *redisCache
+	localTTL   time.Duration
+	instanceID string
+	channel    string
+	metric     prometheus.CacheMetric
+	cancel     context.CancelFunc
+}
+
+func newTieredCache(remote *redisCache, cfg *Config) (*tieredCache, error) {
+	local, err := NewMemoryCache(cfg.MemoryMaxEntries, EvictionPolicy(cfg.MemoryEvictionPolicy))
+	if err != nil {
+		return nil, err
+	}
+	return startTieredCache(remote, local.(*memoryCache), cfg.NearTTL), nil
+}
+
+func startTieredCache(remote *redisCache, local *memoryCache, localTTL time.Duration) *tieredCache {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &tieredCache{
+		local:      local,
+		remote:     remote,
+		localTTL:   localTTL,
+		instanceID: uuid.NewString(),
+		channel:    remote.namespacedKey(invalidationChannelSuffix),
+		metric:     remote.metric,
+		cancel:     cancel,
+	}
+	t.subscribe(ctx)
+	return t
+}
+
+func (t *tieredCache) Get(ctx context.Context, key string, data interface{}, tag *string) error {
+	txn := t.metric.NewCacheGetLatencyTransaction(tag)
+	defer txn.End()
+	if value, err := t.local.getBytes(key); err == nil && scanValue(value, data) == nil {
+		t.metric.CountCacheHit(tag)
+		t.metric.CountCacheLocalHit(tag)
+		return nil
+	}
+
+	value, ttl, err := t.remote.getBytesWithTTL(ctx, key)
+	if err == nil {
+		t.storeLocal(key, value, ttl)
+		err = scanValue(value, data)
+	}
+	if err != nil {
+		t.metric.CountCacheMiss(tag)
+		return err
+	}
+	t.metric.CountCacheHit(tag)
+	t.metric.CountCacheRemoteHit(tag)
+	return nil
+}
+
+func (t *tieredCache) Set(
+	ctx context.Context,
+	key string,
+	data interface{},
+	expire time.Duration,
+	tag *string,
+) error {
+	// Drop the local copy first, so a failed write does not leave a stale value behind
+	//nolint: errcheck
+	t.local.Del(ctx, key)
+	if err := t.remote.Set(ctx, key, data, expire, tag); err != nil {
+		return err
+	}
+	t.publish(ctx, invalidation{Key: key})
+	return nil
+}
+
+func (t *tieredCache) RemoveHashKey(ctx context.Context, key string) error {
+	return t.remote.RemoveHashKey(ctx, key)
+}
+
+func (t *tieredCache) HGet(ctx context.Context, key, field string, data interface{}) error {
+	return t.remote.HGet(ctx, key, field, data)
+}
+
+func (t *tieredCache) HSet(
+	ctx context.Context,
+	key, field string,
+	data interface{},
+	expire time.Duration,
+) error {
+	return t.remote.HSet(ctx, key, field, data, expire)
+}
+
+func (t *tieredCache) DelKeysWithPattern(ctx context.Context, pattern string) error {
+	_, err := t.UnlinkKeysWithPattern(ctx, pattern)
+	return err
+}
+
+func (t *tieredCache) UnlinkKeysWithPattern(ctx context.Context, pattern string) (int64, error) {
+	var deleted atomic.Int64
+	err := t.unlinkKeysWithPattern(ctx, pattern, func(n int64) {
+		deleted.Add(n)
+	})
+	return deleted.Load(), err
+}
+
+func (t *tieredCache) UnlinkKeysWithPatternAsync(ctx context.Context, pattern string) *Deletion {
+	return runDeletion(ctx, func(ctx context.Context, progress func(deleted int64)) error {
+		return t.unlinkKeysWithPattern(ctx, pattern, progress)
+	})
+}
+
+// unlinkKeysWithPattern counts the keys deleted from Redis, local copies are dropped on all instances.
+func (t *tieredCache) unlinkKeysWithPattern(
+	ctx context.Context,
+	pattern string,
+	progress func(deleted int64),
+) error {
+	//nolint: errcheck
+	t.local.UnlinkKeysWithPattern(ctx, pattern)
+	err := t.remote.unlinkKeysWithPattern(ctx, pattern, progress)
+	t.publish(context.WithoutCancel(ctx), invalidation{Pattern: pattern})
+	return err
+}
+
+func (t *tieredCache) Del(ctx context.Context, key string) error {
+	//nolint: errcheck
+	t.local.Del(ctx, key)
+	err := t.remote.Del(ctx, key)
+	t.publish(ctx, invalidation{Key: key})
+	return err
+}
+
+func (t *tieredCache) SetWithTags(
+	ctx context.Context,
+	key string,
+	data interface{},
+	expire time.Duration,
+	tag *string,
+	invalidationTags ...string,
+) error {
+	//nolint: errcheck
+	t.local.Del(ctx, key)
+	if err := t.remote.SetWithTags(ctx, key, data, expire, tag, invalidationTags...); err != nil {
+		return err
+	}
+	t.publish(ctx, invalidation{Key: key})
+	return nil
+}
+
+func (t *tieredCache) InvalidateTags(ctx context.Context, invalidationTags ...string) error {
+	keys, err := t.remote.invalidateTags(ctx, invalidationTags...)
+	if len(keys) > 0 {
+		t.local.delKeys(keys)
+		t.publish(ctx, invalidation{Keys: keys})
+	}
+	return err
+}
+
+func (t *tieredCache) MGet(
+	ctx context.Context,
+	keys []string,
+	data []interface{},
+	tag *string,
+) ([]bool, error) {
+	if len(keys) != len(data) {
+		return nil, fmt.Errorf("[Cache] MGet got %d keys but %d destinations", len(keys), len(data))
+	}
+	txn := t.metric.NewCacheGetLatencyTransaction(tag)
+	defer txn.End()
+	values := t.local.getBytesMany(keys)
+	var remoteKeys []string
+	var remoteIndexes []int
+	for i, value := range values {
+		if value != nil {
+			t.metric.CountCacheLocalHit(tag)
+			continue
+		}
+		remoteKeys = append(remoteKeys, keys[i])
+		remoteIndexes = append(remoteIndexes, i)
+	}
+
+	if len(remoteKeys) > 0 {
+		remoteValues, ttls, err := t.remote.getBytesMany(ctx, remoteKeys, true)
+		if err != nil {
+			return nil, err
+		}
+		for i, value := range remoteValues {
+			if value == nil {
+				continue
+			}
+			t.metric.CountCacheRemoteHit(tag)
+			t.storeLocal(remoteKeys[i], value, ttls[i])
+			values[remoteIndexes[i]] = value
+		}
+	}
+	return scanValues(values, data, tag, t.metric)
+}
+
+func (t *tieredCache) MSet(ctx context.Context, entries []Entry, tag *string) error {
+	keys := make([]string, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.Key
+	}
+	t.local.delKeys(keys)
+	if err := t.remote.MSet(ctx, entries, tag); err != nil {
+		return err
+	}
+	t.publish(ctx, invalidation{Keys: keys})
+	return nil
+}
+
+func (t *tieredCache) MDel(ctx context.Context, keys ...string) error {
+	t.local.delKeys(keys)
+	err := t.remote.MDel(ctx, keys...)
+	t.publish(ctx, invalidation{Keys: keys})
+	return err
+}
+
+func (t *tieredCache) HGetAll(ctx context.Context, key string) (Hash, error) {
+	return t.remote.HGetAll(ctx, key)
+}
+
+func (t *tieredCache) HMSet(
+	ctx context.Context,
+	key string,
+	values map[string]interface{},
+	expire time.Duration,
+) error {
+	return t.remote.HMSet(ctx, key, values, expire)
+}
+
+func (t *tieredCache) HDel(ctx context.Context, key string, fields ...string) error {
+	return t.remote.HDel(ctx, key, fields...)
+}
+
+func (t *tieredCache) Incr(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	return t.IncrBy(ctx, key, 1, expire)
+}
+
+// IncrBy changes a plain value, so local copies of the counter are invalidated.
+func (t *tieredCache) IncrBy(ctx context.Context, key string, value int64, expire time.Duration) (int64, error) {
+	//nolint: errcheck
+	t.local.Del(ctx, key)
+	counter, err := t.remote.IncrBy(ctx, key, value, expire)
+	if err != nil {
+		return counter, err
+	}
+	t.publish(ctx, invalidation{Key: key})
+	return counter, nil
+}
+
+func (t *tieredCache) Decr(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	return t.IncrBy(ctx, key, -1, expire)
+}
+
+// Expire invalidates local copies, so they do not outlive the new expiration.
+func (t *tieredCache) Expire(ctx context.Context, key string, expire time.Duration) error {
+	//nolint: errcheck
+	t.local.Del(ctx, key)
+	err := t.remote.Expire(ctx, key, expire)
+	t.publish(ctx, invalidation{Key: key})
+	return err
+}
+
+func (t *tieredCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return t.remote.TTL(ctx, key)
+}
+
+func (t *tieredCache) ZAdd(ctx context.Context, key string, expire time.Duration, members ...ZMember) error {
+	return t.remote.ZAdd(ctx, key, expire, members...)
+}
+
+func (t *tieredCache) ZRangeByScore(ctx context.Context, key string, by ZRangeBy) ([]ZMember, error) {
+	return t.remote.ZRangeByScore(ctx, key, by)
+}
+
+func (t *tieredCache) ZRem(ctx context.Context, key string, members ...string) error {
+	return t.remote.ZRem(ctx, key, members...)
+}
+
+// BumpVersion also drops local copies of the group and makes other instances read the new version right away.
+func (t *tieredCache) BumpVersion(ctx context.Context, group string) error {
+	if err := t.remote.BumpVersion(ctx, group); err != nil {
+		return err
+	}
+	//nolint: errcheck
+	t.local.dropGroup(ctx, group)
+	t.publish(ctx, invalidation{Group: group})
+	return nil
+}
+
+// storeLocal keeps a local copy of a remote value, which does not outlive the remote key.
+func (t *tieredCache) storeLocal(key string, value []byte, remoteTTL time.Duration) {
+	expire := t.localTTL
+	switch {
+	case remoteTTL == NoExpiration:
+	case remoteTTL <= 0:
+		// The remote key is expiring right now
+		return
+	case expire <= 0 || remoteTTL < expire:
+		expire = remoteTTL
+	}
+	t.local.setBytes(key, value, expire)
+}
+
+// Close stops listening to invalidations of other instances.
+func (t *tieredCache) Close() {
+	t.cancel()
+}
+
+func (t *tieredCache) publish(ctx context.Context, msg invalidation) {
+	msg.Origin = t.instanceID
+	payload, err := json.Marshal(msg)
+	must.NotFail(err)
+	if err := t.remote.client.Publish(ctx, t.channel, payload).Err(); err != nil {
+		log.Printf("[Cache] could not publish invalidation of %s%s. Error: %s", msg.Key, msg.Pattern, err.Error())
+	}
+}
+
+func (t *tieredCache) subscribe(ctx context.Context) {
+	pubsub := t.remote.client.Subscribe(ctx, t.channel)
+	go func() {
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				t.invalidate(ctx, message.Payload)
+			}
+		}
+	}()
+}
+
+func (t *tieredCache) invalidate(ctx context.Context, payload string) {
+	var msg invalidation
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		log.Printf("[Cache] could not parse invalidation %s. Error: %s", payload, err.Error())
+		return
+	}
+	if msg.Origin == t.instanceID {
+		return
+	}
+	if msg.Key != "" {
+		//nolint: errcheck
+		t.local.Del(ctx, msg.Key)
+	}
+	if len(msg.Keys) > 0 {
+		t.local.delKeys(msg.Keys)
+	}
+	if msg.Pattern != "" {
+		//nolint: errcheck
+		t.local.DelKeysWithPattern(ctx, msg.Pattern)
+	}
+	if msg.Group != "" {
+		t.remote.versions.forget(msg.Group)
+		//nolint: errcheck
+		t.local.dropGroup(ctx, msg.Group)
+	}
+}
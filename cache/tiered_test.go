@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func newTieredCacheForTest(t *testing.T, mr *miniredis.Miniredis) *tieredCache {
+	t.Helper()
+
+	remote := newMiniRedisForTest(t, mr)
+	local, err := NewMemoryCache(0, EvictionPolicyLRU)
+	require.NoError(t, err)
+	tiered := startTieredCache(remote, local.(*memoryCache), time.Minute)
+	t.Cleanup(tiered.Close)
+	return tiered
+}
+
+func TestTieredCache_Invalidation(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	podA := newTieredCacheForTest(t, mr)
+	podB := newTieredCacheForTest(t, mr)
+
+	r.NoError(podA.Set(ctx, "key", "v1", time.Minute, nil))
+	var value string
+	r.NoError(podB.Get(ctx, "key", &value, nil))
+	r.Equal("v1", value)
+	// The value is served by the local tier, even after Redis lost it
+	mr.FlushAll()
+	r.NoError(podB.Get(ctx, "key", &value, nil))
+	r.Equal("v1", value)
+
+	r.NoError(podA.Set(ctx, "key", "v2", time.Minute, nil))
+	r.Eventually(func() bool {
+		return podB.Get(ctx, "key", &value, nil) == nil && value == "v2"
+	}, time.Second, 10*time.Millisecond)
+
+	r.NoError(podB.Get(ctx, "key", &value, nil))
+	r.NoError(podA.DelKeysWithPattern(ctx, "k*"))
+	r.Eventually(func() bool {
+		return podB.Get(ctx, "key", &value, nil) == ErrCacheMiss
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTieredCache_LocalCopyDoesNotOutliveRemoteKey(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	testCases := map[string]struct {
+		remoteExpire time.Duration
+		maxLocalTTL  time.Duration
+	}{
+		"remote key expires before the local TTL": {
+			remoteExpire: time.Second,
+			maxLocalTTL:  time.Second,
+		},
+		"remote key expires after the local TTL": {
+			remoteExpire: time.Hour,
+			maxLocalTTL:  time.Minute,
+		},
+		"remote key does not expire": {
+			remoteExpire: 0,
+			maxLocalTTL:  time.Minute,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			tiered := newTieredCacheForTest(t, miniredis.RunT(t))
+			r.NoError(tiered.remote.Set(ctx, "get", "value", tc.remoteExpire, nil))
+			r.NoError(tiered.remote.Set(ctx, "mget", "value", tc.remoteExpire, nil))
+
+			var value string
+			r.NoError(tiered.Get(ctx, "get", &value, nil))
+			_, err := tiered.MGet(ctx, []string{"mget"}, []interface{}{&value}, nil)
+			r.NoError(err)
+
+			for _, key := range []string{"get", "mget"} {
+				ttl, err := tiered.local.TTL(ctx, key)
+				r.NoError(err)
+				r.Positive(ttl)
+				r.LessOrEqual(ttl, tc.maxLocalTTL)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/saigontechnology/go-shared-packages/prometheus"
+)
+
+// Typed is a type-safe wrapper around Cache. Values are encoded with the given Codec and
+// stored as bytes, so namespace prefixing and hit/miss metrics of the underlying Cache still apply.
+type Typed[T any] struct {
+	cache  Cache
+	codec  Codec
+	cfg    *typedConfig
+	metric prometheus.CacheMetric
+	group  singleflight.Group
+}
+
+type typedConfig struct {
+	loadTimeout time.Duration
+	loadLockTTL time.Duration
+	softTTL     time.Duration
+	negativeTTL time.Duration
+}
+
+// enveloped tells whether values are stored in an entry carrying their soft expiration and absence.
+func (cfg *typedConfig) enveloped() bool {
+	return cfg.softTTL > 0 || cfg.negativeTTL > 0
+}
+
+type TypedOption func(cfg *typedConfig)
+
+// WithLoadTimeout bounds a loader call of GetOrLoad, 30 seconds by default. The call is shared by the callers
+// missing the same key, so it is not cancelled with the context of any of them.
+func WithLoadTimeout(timeout time.Duration) TypedOption {
+	return func(cfg *typedConfig) {
+		cfg.loadTimeout = timeout
+	}
+}
+
+// WithLoadLock makes GetOrLoad take a Redis lock for the given duration before calling the loader,
+// so only one pod loads a missing key at a time. Other pods wait for the value up to the lock duration.
+func WithLoadLock(ttl time.Duration) TypedOption {
+	return func(cfg *typedConfig) {
+		cfg.loadLockTTL = ttl
+	}
+}
+
+// WithStaleWhileRevalidate makes values stale softTTL after they are set. GetOrLoad returns a stale value
+// and reloads it in the background, the expiration given to Set and GetOrLoad is the hard TTL of the value.
+// All Typed sharing keys must use the same WithStaleWhileRevalidate and WithNegativeCaching options.
+func WithStaleWhileRevalidate(softTTL time.Duration) TypedOption {
+	return func(cfg *typedConfig) {
+		cfg.softTTL = softTTL
+	}
+}
+
+// WithNegativeCaching caches for ttl the absence of keys whose loader returned ErrKnownAbsent,
+// Get and GetOrLoad return ErrKnownAbsent for them instead of calling the loader again.
+func WithNegativeCaching(ttl time.Duration) TypedOption {
+	return func(cfg *typedConfig) {
+		cfg.negativeTTL = ttl
+	}
+}
+
+// NewTyped creates a Typed cache. JSONCodec is used when codec is nil.
+func NewTyped[T any](c Cache, codec Codec, options ...TypedOption) *Typed[T] {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	cfg := &typedConfig{loadTimeout: defaultLoadTimeout}
+	for _, option := range options {
+		option(cfg)
+	}
+	return &Typed[T]{
+		cache:  c,
+		codec:  codec,
+		cfg:    cfg,
+		metric: prometheus.GetCacheMetric(),
+	}
+}
+
+// Get returns ErrCacheMiss when the key does not exist and ErrKnownAbsent when it is cached as absent.
+// Stale values are returned as well.
+func (t *Typed[T]) Get(ctx context.Context, key string, tag *string) (T, error) {
+	e, err := t.getEntry(ctx, key, tag)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return t.decode(e)
+}
+
+// getFresh is Get, except that stale values are reported as a miss.
+func (t *Typed[T]) getFresh(ctx context.Context, key string, tag *string) (T, error) {
+	e, err := t.getEntry(ctx, key, tag)
+	if err == nil && e.stale(time.Now()) {
+		err = ErrCacheMiss
+	}
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return t.decode(e)
+}
+
+func (t *Typed[T]) getEntry(ctx context.Context, key string, tag *string) (*entry, error) {
+	var raw []byte
+	if err := t.cache.Get(ctx, key, &raw, tag); err != nil {
+		if errors.Is(err, ErrCacheMiss) {
+			return nil, ErrCacheMiss
+		}
+		return nil, err
+	}
+	// dumpCache does not return an error on miss
+	if len(raw) == 0 {
+		return nil, ErrCacheMiss
+	}
+	if !t.cfg.enveloped() {
+		return &entry{value: raw}, nil
+	}
+	return decodeEntry(raw)
+}
+
+func (t *Typed[T]) decode(e *entry) (T, error) {
+	var value T
+	if e.absent {
+		return value, ErrKnownAbsent
+	}
+	if err := t.codec.Unmarshal(e.value, &value); err != nil {
+		return value, err
+	}
+	return value, nil
+}
+
+func (t *Typed[T]) Set(
+	ctx context.Context,
+	key string,
+	value T,
+	expire time.Duration,
+	tag *string,
+) error {
+	raw, err := t.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if t.cfg.enveloped() {
+		e := &entry{value: raw}
+		if t.cfg.softTTL > 0 {
+			e.softExpireAt = time.Now().Add(t.cfg.softTTL)
+		}
+		raw = e.encode()
+	}
+	return t.cache.Set(ctx, key, raw, expire, tag)
+}
+
+// SetAbsent caches the absence of key for the WithNegativeCaching duration.
+func (t *Typed[T]) SetAbsent(ctx context.Context, key string, tag *string) error {
+	if t.cfg.negativeTTL <= 0 {
+		return errors.New("[Cache] negative caching is not enabled")
+	}
+	e := &entry{absent: true}
+	return t.cache.Set(ctx, key, e.encode(), t.cfg.negativeTTL, tag)
+}
+
+// GetMany returns values of existing keys only, missing and absent keys are not included in the result.
+func (t *Typed[T]) GetMany(ctx context.Context, keys []string, tag *string) (map[string]T, error) {
+	raws := make([][]byte, len(keys))
+	data := make([]interface{}, len(keys))
+	for i := range raws {
+		data[i] = &raws[i]
+	}
+	found, err := t.cache.MGet(ctx, keys, data, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]T, len(keys))
+	for i, key := range keys {
+		if !found[i] || len(raws[i]) == 0 {
+			continue
+		}
+		e := &entry{value: raws[i]}
+		if t.cfg.enveloped() {
+			if e, err = decodeEntry(raws[i]); err != nil {
+				return nil, err
+			}
+		}
+		value, err := t.decode(e)
+		if errors.Is(err, ErrKnownAbsent) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+func (t *Typed[T]) Delete(ctx context.Context, key string) error {
+	return t.cache.Del(ctx, key)
+}
@@ -0,0 +1,71 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/saigontechnology/go-shared-packages/cache"
+)
+
+type typedTestValue struct {
+	ID   int
+	Name string
+}
+
+func TestTyped(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name  string
+		codec cache.Codec
+	}{
+		{
+			name:  "JSON",
+			codec: cache.JSONCodec{},
+		},
+		{
+			name:  "Msgpack",
+			codec: cache.MsgpackCodec{},
+		},
+		{
+			name:  "Gob",
+			codec: cache.GobCodec{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			ctx := context.Background()
+			typed := cache.NewTyped[typedTestValue](cache.NewMiniRedisForTest(t), tc.codec)
+
+			_, err := typed.Get(ctx, "missing", nil)
+			r.ErrorIs(err, cache.ErrCacheMiss)
+
+			value := typedTestValue{ID: 1, Name: "one"}
+			r.NoError(typed.Set(ctx, "one", value, time.Minute, nil))
+			actual, err := typed.Get(ctx, "one", nil)
+			r.NoError(err)
+			r.Equal(value, actual)
+
+			values, err := typed.GetMany(ctx, []string{"one", "missing"}, nil)
+			r.NoError(err)
+			r.Equal(map[string]typedTestValue{"one": value}, values)
+
+			r.NoError(typed.Delete(ctx, "one"))
+			_, err = typed.Get(ctx, "one", nil)
+			r.ErrorIs(err, cache.ErrCacheMiss)
+		})
+	}
+}
+
+func TestTyped_DumpCache(t *testing.T) {
+	t.Parallel()
+	typed := cache.NewTyped[typedTestValue](cache.GetProvider().DumpCache(), nil)
+	_, err := typed.Get(context.Background(), "any", nil)
+	require.ErrorIs(t, err, cache.ErrCacheMiss)
+}
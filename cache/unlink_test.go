@@ -0,0 +1,52 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/saigontechnology/go-shared-packages/cache"
+)
+
+func TestCache_UnlinkKeysWithPattern(t *testing.T) {
+	t.Parallel()
+	for _, backend := range backendsForTest() {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			ctx := context.Background()
+			c := backend.newCache(t)
+			entries := make([]cache.Entry, 0, 2100)
+			for i := 0; i < 2000; i++ {
+				entries = append(entries, cache.Entry{Key: fmt.Sprintf("user:%d", i), Data: i, Expire: time.Minute})
+			}
+			for i := 0; i < 100; i++ {
+				entries = append(entries, cache.Entry{Key: fmt.Sprintf("order:%d", i), Data: i, Expire: time.Minute})
+			}
+			r.NoError(c.MSet(ctx, entries, nil))
+
+			deleted, err := c.UnlinkKeysWithPattern(ctx, "user:1*")
+			r.NoError(err)
+			r.Equal(int64(1111), deleted)
+
+			deletion := c.UnlinkKeysWithPatternAsync(ctx, "user:*")
+			deleted, err = deletion.Wait()
+			r.NoError(err)
+			r.Equal(int64(889), deleted)
+			r.Equal(deleted, deletion.Deleted())
+
+			var value int
+			r.NoError(c.Get(ctx, "order:1", &value, nil))
+
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+			_, err = c.UnlinkKeysWithPatternAsync(cancelled, "order:*").Wait()
+			r.ErrorIs(err, context.Canceled)
+			r.NoError(c.Get(ctx, "order:1", &value, nil))
+		})
+	}
+}
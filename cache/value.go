@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"encoding"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/saigontechnology/go-shared-packages/prometheus"
+)
+
+// encodeValue converts data to bytes the same way go-redis does when it sends a command argument,
+// so in-process backends store exactly what Redis would store.
+func encodeValue(data interface{}) ([]byte, error) {
+	switch v := data.(type) {
+	case nil:
+		return []byte{}, nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return append([]byte{}, v...), nil
+	case bool:
+		return encodeBool(v), nil
+	case time.Time:
+		return v.AppendFormat(nil, time.RFC3339Nano), nil
+	case time.Duration:
+		return strconv.AppendInt(nil, v.Nanoseconds(), 10), nil
+	case encoding.BinaryMarshaler:
+		return v.MarshalBinary()
+	case net.IP:
+		return append([]byte{}, v...), nil
+	}
+
+	rv := reflect.ValueOf(data)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		return []byte(rv.String()), nil
+	case reflect.Bool:
+		return encodeBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(nil, rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.AppendUint(nil, rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.AppendFloat(nil, rv.Float(), 'f', -1, 64), nil
+	default:
+		return nil, fmt.Errorf("redis: can't marshal %T (implement encoding.BinaryMarshaler)", data)
+	}
+}
+
+func encodeBool(v bool) []byte {
+	if v {
+		return []byte("1")
+	}
+	return []byte("0")
+}
+
+// scanValue is the reverse of valueCodec.encode. Values without a codec header are scanned the way go-redis does,
+// it supports the same destinations as go-redis Scan.
+func scanValue(value []byte, data interface{}) error {
+	if decoded, err := decodeValue(value, data); decoded {
+		return err
+	}
+	return scanRawValue(value, data)
+}
+
+func scanRawValue(value []byte, data interface{}) error {
+	return redis.NewStringResult(string(value), nil).Scan(data)
+}
+
+// scanValues scans values into data and counts a hit or a miss for each key, a nil value is a miss.
+func scanValues(
+	values [][]byte,
+	data []interface{},
+	tag *string,
+	metric prometheus.CacheMetric,
+) ([]bool, error) {
+	found := make([]bool, len(values))
+	for i, value := range values {
+		if value == nil {
+			metric.CountCacheMiss(tag)
+			continue
+		}
+		if err := scanValue(value, data[i]); err != nil {
+			return nil, err
+		}
+		metric.CountCacheHit(tag)
+		found[i] = true
+	}
+	return found, nil
+}
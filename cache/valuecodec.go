@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"fmt"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	CodecJSON     = "json"
+	CodecMsgpack  = "msgpack"
+	CodecProtobuf = "protobuf"
+	CodecGob      = "gob"
+
+	CompressionNone   = "none"
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+)
+
+// Encoded values start with headerMarker followed by a header byte, the low nibble of the header
+// is the format and the high nibble is the compression. 0xC1 is never used by msgpack and is invalid in UTF-8,
+// so values stored before the codec layer existed are not mistaken for encoded values and still read as they are.
+const (
+	headerMarker byte = 0xC1
+	headerLength      = 2
+
+	formatRaw      byte = 0
+	formatJSON     byte = 1
+	formatMsgpack  byte = 2
+	formatProtobuf byte = 3
+	formatGob      byte = 4
+
+	compressionNone   byte = 0
+	compressionSnappy byte = 1
+	compressionZstd   byte = 2
+)
+
+var (
+	formats = map[string]byte{
+		CodecJSON:     formatJSON,
+		CodecMsgpack:  formatMsgpack,
+		CodecProtobuf: formatProtobuf,
+		CodecGob:      formatGob,
+	}
+	formatCodecs = map[byte]Codec{
+		formatJSON:     JSONCodec{},
+		formatMsgpack:  MsgpackCodec{},
+		formatProtobuf: ProtobufCodec{},
+		formatGob:      GobCodec{},
+	}
+	compressions = map[string]byte{
+		CompressionNone:   compressionNone,
+		CompressionSnappy: compressionSnappy,
+		CompressionZstd:   compressionZstd,
+	}
+
+	// zstd encoders and decoders are safe for concurrent use of EncodeAll and DecodeAll
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// valueCodec encodes values before they are stored. Values go-redis can encode (strings, bytes, numbers...)
+// are stored as go-redis would store them, other values are marshaled with the configured codec.
+// Values larger than the threshold are compressed.
+type valueCodec struct {
+	format      byte
+	compression byte
+	threshold   int
+}
+
+func newValueCodec(codec, compression string, threshold int) (*valueCodec, error) {
+	format, ok := formats[codec]
+	if !ok {
+		return nil, fmt.Errorf("[Cache] codec %s is not supported", codec)
+	}
+	c, ok := compressions[compression]
+	if !ok {
+		return nil, fmt.Errorf("[Cache] compression %s is not supported", compression)
+	}
+	return &valueCodec{
+		format:      format,
+		compression: c,
+		threshold:   threshold,
+	}, nil
+}
+
+// defaultValueCodec marshals values with JSON and does not compress them.
+func defaultValueCodec() *valueCodec {
+	return &valueCodec{format: formatJSON, compression: compressionNone}
+}
+
+func (c *valueCodec) encode(data interface{}) ([]byte, error) {
+	format := formatRaw
+	payload, err := encodeValue(data)
+	if err != nil {
+		format = c.format
+		payload, err = formatCodecs[format].Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	compression := compressionNone
+	if c.compression != compressionNone && len(payload) >= c.threshold {
+		compression = c.compression
+		payload = compress(compression, payload)
+	}
+
+	// Raw values are kept as they are, so they are readable by any Redis client
+	raw := format == formatRaw && compression == compressionNone
+	if raw && (len(payload) == 0 || payload[0] != headerMarker) {
+		return payload, nil
+	}
+	value := make([]byte, 0, headerLength+len(payload))
+	value = append(value, headerMarker, compression<<4|format)
+	return append(value, payload...), nil
+}
+
+// decodeValue reads values written by any valueCodec, whatever its configuration.
+func decodeValue(value []byte, data interface{}) (bool, error) {
+	if len(value) < headerLength || value[0] != headerMarker {
+		return false, nil
+	}
+	format := value[1] & 0x0F
+	compression := value[1] >> 4
+	codec, ok := formatCodecs[format]
+	if format != formatRaw && !ok {
+		return false, nil
+	}
+
+	payload, err := decompress(compression, value[headerLength:])
+	if err != nil {
+		return true, err
+	}
+	if format == formatRaw {
+		return true, scanRawValue(payload, data)
+	}
+	return true, codec.Unmarshal(payload, data)
+}
+
+func compress(compression byte, payload []byte) []byte {
+	switch compression {
+	case compressionSnappy:
+		return snappy.Encode(nil, payload)
+	case compressionZstd:
+		return zstdEncoder.EncodeAll(payload, nil)
+	default:
+		return payload
+	}
+}
+
+func decompress(compression byte, payload []byte) ([]byte, error) {
+	switch compression {
+	case compressionNone:
+		return payload, nil
+	case compressionSnappy:
+		return snappy.Decode(nil, payload)
+	case compressionZstd:
+		return zstdDecoder.DecodeAll(payload, nil)
+	default:
+		return nil, fmt.Errorf("[Cache] compression %d is not supported", compression)
+	}
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type codecTestValue struct {
+	Name  string
+	Count int
+}
+
+func TestValueCodec_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		codec       string
+		compression string
+	}{
+		{name: "json", codec: CodecJSON, compression: CompressionNone},
+		{name: "msgpack with snappy", codec: CodecMsgpack, compression: CompressionSnappy},
+		{name: "gob with zstd", codec: CodecGob, compression: CompressionZstd},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			codec, err := newValueCodec(tc.codec, tc.compression, 64)
+			r.NoError(err)
+
+			for _, name := range []string{"small", strings.Repeat("large", 100)} {
+				value, err := codec.encode(codecTestValue{Name: name, Count: 2})
+				r.NoError(err)
+				var decoded codecTestValue
+				r.NoError(scanValue(value, &decoded))
+				r.Equal(codecTestValue{Name: name, Count: 2}, decoded)
+
+				value, err = codec.encode(name)
+				r.NoError(err)
+				var s string
+				r.NoError(scanValue(value, &s))
+				r.Equal(name, s)
+			}
+		})
+	}
+}
+
+func TestValueCodec_Compression(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	codec, err := newValueCodec(CodecJSON, CompressionZstd, 1024)
+	r.NoError(err)
+
+	// Small raw values are stored as go-redis would store them
+	value, err := codec.encode("small")
+	r.NoError(err)
+	r.Equal([]byte("small"), value)
+
+	large := strings.Repeat("a", 4096)
+	value, err = codec.encode(large)
+	r.NoError(err)
+	r.Less(len(value), len(large))
+	var s string
+	r.NoError(scanValue(value, &s))
+	r.Equal(large, s)
+
+	_, err = newValueCodec("xml", CompressionNone, 0)
+	r.Error(err)
+	_, err = newValueCodec(CodecJSON, "lz4", 0)
+	r.Error(err)
+}
+
+func TestValueCodec_Protobuf(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	codec, err := newValueCodec(CodecProtobuf, CompressionNone, 0)
+	r.NoError(err)
+
+	value, err := codec.encode(wrapperspb.String("proto"))
+	r.NoError(err)
+	var message *wrapperspb.StringValue
+	r.NoError(scanValue(value, &message))
+	r.Equal("proto", message.GetValue())
+
+	_, err = codec.encode(codecTestValue{})
+	r.Error(err)
+}
+
+func TestRedisCache_LegacyValues(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	c := newMiniRedisForTest(t, mr)
+	c.codec, _ = newValueCodec(CodecMsgpack, CompressionSnappy, 0)
+
+	// Values written before the codec layer are read as they are
+	r.NoError(mr.Set("test_legacy", "42"))
+	var n int
+	r.NoError(c.Get(ctx, "legacy", &n, nil))
+	r.Equal(42, n)
+
+	r.NoError(c.Set(ctx, "struct", codecTestValue{Name: "a", Count: 1}, time.Minute, nil))
+	var decoded codecTestValue
+	r.NoError(c.Get(ctx, "struct", &decoded, nil))
+	r.Equal(codecTestValue{Name: "a", Count: 1}, decoded)
+
+	r.NoError(c.HSet(ctx, "hash", "field", codecTestValue{Name: "b"}, time.Minute))
+	r.NoError(c.HGet(ctx, "hash", "field", &decoded))
+	r.Equal(codecTestValue{Name: "b"}, decoded)
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const versionKeyPrefix = "version:"
+
+type cachedVersion struct {
+	version   int64
+	fetchedAt time.Time
+}
+
+// groupVersions keeps the generation of versioned groups. Versions live in Redis and are cached in process
+// for localTTL, so other instances see a bumped version after localTTL at most.
+type groupVersions struct {
+	groups   map[string]struct{}
+	localTTL time.Duration
+
+	mu     sync.Mutex
+	cached map[string]cachedVersion
+}
+
+func newGroupVersions(groups []string, localTTL time.Duration) *groupVersions {
+	v := &groupVersions{
+		groups:   make(map[string]struct{}, len(groups)),
+		localTTL: localTTL,
+		cached:   make(map[string]cachedVersion),
+	}
+	for _, group := range groups {
+		if group != "" {
+			v.groups[group] = struct{}{}
+		}
+	}
+	return v
+}
+
+func (v *groupVersions) versioned(group string) bool {
+	_, ok := v.groups[group]
+	return ok
+}
+
+// get falls back to the last known version when Redis can not be read.
+func (v *groupVersions) get(ctx context.Context, r *redisCache, group string) (int64, error) {
+	v.mu.Lock()
+	cached, ok := v.cached[group]
+	v.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < v.localTTL {
+		return cached.version, nil
+	}
+
+	version, err := r.client.Get(ctx, r.internalKey(versionKeyPrefix+group)).Int64()
+	if errors.Is(err, redis.Nil) {
+		version, err = 0, nil
+	}
+	if err != nil {
+		if ok {
+			log.Printf("[Cache] could not get version of %s, using version %d. Error: %s", group, cached.version, err)
+			return cached.version, nil
+		}
+		return 0, err
+	}
+	v.set(group, version)
+	return version, nil
+}
+
+func (v *groupVersions) set(group string, version int64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.cached[group] = cachedVersion{version: version, fetchedAt: time.Now()}
+}
+
+func (v *groupVersions) forget(group string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.cached, group)
+}
+
+func (r *redisCache) BumpVersion(ctx context.Context, group string) error {
+	if !r.versions.versioned(group) {
+		return fmt.Errorf("[Cache] group %s is not versioned", group)
+	}
+	s := r.newrelicRedisSegment(ctx, "BumpVersion")
+	defer s.End()
+	version, err := r.client.Incr(ctx, r.internalKey(versionKeyPrefix+group)).Result()
+	if err != nil {
+		return err
+	}
+	r.versions.set(group, version)
+	return nil
+}
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisCache_BumpVersion(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	podA := newMiniRedisForTest(t, mr)
+	podA.versions = newGroupVersions([]string{"user"}, time.Minute)
+	podB := newMiniRedisForTest(t, mr)
+	podB.versions = newGroupVersions([]string{"user"}, time.Minute)
+
+	r.NoError(podA.Set(ctx, "user:1", "alice", time.Minute, nil))
+	r.NoError(podA.Set(ctx, "order:1", "book", time.Minute, nil))
+	r.True(mr.Exists("test_user:v0:1"))
+	var value string
+	r.NoError(podB.Get(ctx, "user:1", &value, nil))
+
+	r.NoError(podA.BumpVersion(ctx, "user"))
+	r.ErrorIs(podA.Get(ctx, "user:1", &value, nil), ErrCacheMiss)
+	r.NoError(podA.Get(ctx, "order:1", &value, nil))
+	r.Error(podA.BumpVersion(ctx, "order"))
+
+	// Other instances use their local version until it expires
+	r.NoError(podB.Get(ctx, "user:1", &value, nil))
+	podB.versions.forget("user")
+	r.ErrorIs(podB.Get(ctx, "user:1", &value, nil), ErrCacheMiss)
+
+	// Patterns match keys of all versions
+	deleted, err := podA.UnlinkKeysWithPattern(ctx, "user:*")
+	r.NoError(err)
+	r.Equal(int64(1), deleted)
+}
+
+func TestTieredCache_BumpVersion(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	podA := newTieredCacheForTest(t, mr)
+	podA.remote.versions = newGroupVersions([]string{"user"}, time.Minute)
+	podB := newTieredCacheForTest(t, mr)
+	podB.remote.versions = newGroupVersions([]string{"user"}, time.Minute)
+
+	r.NoError(podA.Set(ctx, "user:1", "alice", time.Minute, nil))
+	var value string
+	r.NoError(podB.Get(ctx, "user:1", &value, nil))
+
+	r.NoError(podA.BumpVersion(ctx, "user"))
+	r.ErrorIs(podA.Get(ctx, "user:1", &value, nil), ErrCacheMiss)
+	r.Eventually(func() bool {
+		return podB.Get(ctx, "user:1", &value, nil) == ErrCacheMiss
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRedisCache_UnlinkKeysWithPatternOfVersionedGroup(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	c := newMiniRedisForTest(t, mr)
+	c.versions = newGroupVersions([]string{"user"}, time.Minute)
+
+	r.NoError(c.Set(ctx, "user:1", "old", time.Minute, nil))
+	r.NoError(c.BumpVersion(ctx, "user"))
+	for _, key := range []string{"user:1", "user:10", "user:2", "user:a:1", "order:1"} {
+		r.NoError(c.Set(ctx, key, key, time.Minute, nil))
+	}
+
+	deleted, err := c.UnlinkKeysWithPattern(ctx, "user:1*")
+	r.NoError(err)
+	r.Equal(int64(3), deleted)
+	r.False(mr.Exists("test_user:v0:1"))
+	r.False(mr.Exists("test_user:v1:1"))
+	r.False(mr.Exists("test_user:v1:10"))
+	r.True(mr.Exists("test_user:v1:2"))
+	r.True(mr.Exists("test_user:v1:a:1"))
+	r.True(mr.Exists("test_order:1"))
+
+	r.NoError(c.DelKeysWithPattern(ctx, "user:?"))
+	var value string
+	r.ErrorIs(c.Get(ctx, "user:2", &value, nil), ErrCacheMiss)
+	r.NoError(c.Get(ctx, "user:a:1", &value, nil))
+}
+
+func TestMemoryCache_BumpVersion(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	c, err := NewMemoryCache(0, EvictionPolicyLRU, "user")
+	r.NoError(err)
+
+	r.NoError(c.Set(ctx, "user:1", "alice", time.Minute, nil))
+	r.NoError(c.Set(ctx, "order:1", "book", time.Minute, nil))
+	r.NoError(c.BumpVersion(ctx, "user"))
+	r.Error(c.BumpVersion(ctx, "order"))
+
+	var value string
+	r.ErrorIs(c.Get(ctx, "user:1", &value, nil), ErrCacheMiss)
+	r.NoError(c.Get(ctx, "order:1", &value, nil))
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type scoreBound struct {
+	score     float64
+	exclusive bool
+}
+
+// parseScoreBound parses a ZRangeBy bound the way Redis does.
+func parseScoreBound(bound string) (scoreBound, error) {
+	b := scoreBound{}
+	if strings.HasPrefix(bound, "(") {
+		b.exclusive = true
+		bound = bound[1:]
+	}
+	score, err := strconv.ParseFloat(bound, 64)
+	if err != nil {
+		return b, fmt.Errorf("ERR min or max is not a float")
+	}
+	b.score = score
+	return b, nil
+}
+
+func (b scoreBound) above(score float64) bool {
+	if b.exclusive {
+		return score > b.score
+	}
+	return score >= b.score
+}
+
+func (b scoreBound) below(score float64) bool {
+	if b.exclusive {
+		return score < b.score
+	}
+	return score <= b.score
+}
+
+// rangeByScore selects members of an in-process sorted set, members with the same score are ordered
+// lexicographically as in Redis.
+func rangeByScore(zset map[string]float64, by ZRangeBy) ([]ZMember, error) {
+	minBound, err := parseScoreBound(by.Min)
+	if err != nil {
+		return nil, err
+	}
+	maxBound, err := parseScoreBound(by.Max)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]ZMember, 0, len(zset))
+	for member, score := range zset {
+		if minBound.above(score) && maxBound.below(score) {
+			members = append(members, ZMember{Member: member, Score: score})
+		}
+	}
+	less := func(a, b ZMember) bool {
+		return a.Score < b.Score || a.Score == b.Score && a.Member < b.Member
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if by.Reverse {
+			return less(members[j], members[i])
+		}
+		return less(members[i], members[j])
+	})
+
+	if by.Offset > 0 {
+		if by.Offset >= int64(len(members)) {
+			return []ZMember{}, nil
+		}
+		members = members[by.Offset:]
+	}
+	if by.Count > 0 && by.Count < int64(len(members)) {
+		members = members[:by.Count]
+	}
+	return members, nil
+}
@@ -1,10 +1,14 @@
 package database
 
 import (
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
+
+	"github.com/saigontechnology/go-shared-packages/env"
 )
 
-type config struct {
+type Config struct {
 	Host     string `default:"your-service-db:3306" envconfig:"DB_HOST"`
 	Port     string `default:"3306"                 envconfig:"DB_PORT"`
 	Name     string `default:"your-db"              envconfig:"DB_NAME"`
@@ -19,10 +23,48 @@ type config struct {
 	MaxOpenConns int `default:"0" envconfig:"DB_MAX_OPEN_CONNS"`
 	// Default is 0, connections are not closed due to a connection's age.
 	ConnMaxLifetime int64 `default:"0" envconfig:"DB_CONN_MAX_LIFETIME"`
+	// LogEnabled logs queries through logger.Logger at LogLevel, one of silent, error, warn or info, ErrorLog is ignored then.
+	// Queries slower than SlowQueryThreshold are logged as warnings, parameters are not logged when LogRedactParams is true
+	LogEnabled         bool          `default:"false" envconfig:"DB_LOG_ENABLED"`
+	LogLevel           string        `default:"warn"  envconfig:"DB_LOG_LEVEL"`
+	SlowQueryThreshold time.Duration `default:"200ms" envconfig:"DB_SLOW_QUERY_THRESHOLD"`
+	LogRedactParams    bool          `default:"true"  envconfig:"DB_LOG_REDACT_PARAMS"`
+	// ReplicaPolicy balances reads between the replicas of this connection, it is one of random, round-robin,
+	// weighted or least-connections. The weight of a replica is its own ReplicaWeight.
+	ReplicaPolicy string `default:"random" envconfig:"DB_REPLICA_POLICY"`
+	ReplicaWeight int    `default:"1"      envconfig:"DB_REPLICA_WEIGHT"`
+	// Replicas are pinged every ReplicaCheckInterval, 0 disables the checks. A replica which does not answer within
+	// ReplicaPingTimeout or lags more than ReplicaMaxLag, 0 means any lag, is removed from rotation until it recovers.
+	// Reading the lag of a MySQL replica needs the REPLICATION CLIENT privilege
+	ReplicaCheckInterval time.Duration `default:"10s" envconfig:"DB_REPLICA_CHECK_INTERVAL"`
+	ReplicaPingTimeout   time.Duration `default:"1s"  envconfig:"DB_REPLICA_PING_TIMEOUT"`
+	ReplicaMaxLag        time.Duration `default:"0"   envconfig:"DB_REPLICA_MAX_LAG"`
+	// PostgresParams are only used by the Postgres connector
+	PostgresParams
 }
 
-func newConfig(name string) (*config, error) {
-	cfg := &config{}
+// PostgresParams are the connection parameters of Postgres besides the address and the credentials.
+// They are shared by the database and migration packages, see PostgresDSN.
+type PostgresParams struct {
+	// SSLMode is one of disable, allow, prefer, require, verify-ca or verify-full
+	SSLMode string `default:"disable" envconfig:"DB_SSL_MODE"`
+	// SSLRootCert, SSLCert and SSLKey are file paths of the CA certificate and of the client certificate and key
+	SSLRootCert     string `default:""                 envconfig:"DB_SSL_ROOT_CERT"`
+	SSLCert         string `default:""                 envconfig:"DB_SSL_CERT"`
+	SSLKey          string `default:""                 envconfig:"DB_SSL_KEY"`
+	TimeZone        string `default:"Asia/Ho_Chi_Minh" envconfig:"DB_TIMEZONE"`
+	SearchPath      string `default:""                 envconfig:"DB_SEARCH_PATH"`
+	ApplicationName string `default:""                 envconfig:"DB_APPLICATION_NAME"`
+	// StatementTimeout aborts statements running longer, 0 means no timeout
+	StatementTimeout time.Duration `default:"0" envconfig:"DB_STATEMENT_TIMEOUT"`
+	// ExtraParams are added to the DSN as they are, e.g. DB_EXTRA_PARAMS=connect_timeout:5,target_session_attrs:read-write
+	ExtraParams map[string]string `default:"" envconfig:"DB_EXTRA_PARAMS"`
+}
+
+// NewConfig reads the configuration of the connection name from the environment,
+// <NAME>_DB_HOST is used before DB_HOST and so on.
+func NewConfig(name string) (*Config, error) {
+	cfg := &Config{}
 	err := envconfig.Process(name, cfg)
 	if err != nil {
 		return nil, err
@@ -30,3 +72,47 @@ func newConfig(name string) (*config, error) {
 
 	return cfg, nil
 }
+
+// Option changes a Provider created by New or a Connector created by NewMysqlDB or NewPostgresDB.
+type Option func(o *options)
+
+type options struct {
+	connector Connector
+	configs   configs
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{configs: configs{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithConnector sets the Connector of a Provider, the MySQL connector is used by default.
+func WithConnector(c Connector) Option {
+	return func(o *options) {
+		o.connector = c
+	}
+}
+
+// WithConfig configures the connection name, the environment is read for connections without a configuration.
+// Fields left at their zero value get the default of their environment variable.
+func WithConfig(name string, cfg Config) Option {
+	return func(o *options) {
+		o.configs[name] = cfg
+	}
+}
+
+// configs holds the configurations given with WithConfig.
+type configs map[string]Config
+
+func (c configs) get(name string) (*Config, error) {
+	if cfg, ok := c[name]; ok {
+		if err := env.SetDefaults(&cfg); err != nil {
+			return nil, err
+		}
+		return &cfg, nil
+	}
+	return NewConfig(name)
+}
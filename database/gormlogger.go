@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/saigontechnology/go-shared-packages/logger"
+)
+
+var logLevels = map[string]gormlogger.LogLevel{
+	"silent": gormlogger.Silent,
+	"error":  gormlogger.Error,
+	"warn":   gormlogger.Warn,
+	"info":   gormlogger.Info,
+}
+
+// gormLogger writes GORM logs through logger.Logger, so they are structured and carry the tracing IDs of the context.
+// Failed queries are errors, slow queries are warnings and other queries are only logged at the info level.
+type gormLogger struct {
+	l             logger.Logger
+	name          string
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+	redactParams  bool
+}
+
+func newGormLogger(l logger.Logger, name string, cfg *Config) (*gormLogger, error) {
+	level, ok := logLevels[cfg.LogLevel]
+	if !ok {
+		return nil, fmt.Errorf("[Database] log level %s is not supported", cfg.LogLevel)
+	}
+	return &gormLogger{
+		l:             l,
+		name:          name,
+		level:         level,
+		slowThreshold: cfg.SlowQueryThreshold,
+		redactParams:  cfg.LogRedactParams,
+	}, nil
+}
+
+func (g *gormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *g
+	copied.level = level
+	return &copied
+}
+
+func (g *gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if g.level >= gormlogger.Info {
+		g.l.Infow(ctx, fmt.Sprintf("[Database] "+msg, data...), "db", g.name)
+	}
+}
+
+func (g *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if g.level >= gormlogger.Warn {
+		g.l.Warnw(ctx, fmt.Sprintf("[Database] "+msg, data...), "db", g.name)
+	}
+}
+
+func (g *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if g.level >= gormlogger.Error {
+		g.l.Errorw(ctx, fmt.Sprintf("[Database] "+msg, data...), "db", g.name)
+	}
+}
+
+func (g *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if g.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	fields := func(sql string, rows int64) []interface{} {
+		return []interface{}{
+			"db", g.name,
+			"sql", sql,
+			"duration_ms", float64(elapsed.Microseconds()) / 1000,
+			"rows", rows,
+		}
+	}
+	switch {
+	case err != nil && g.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		g.l.Errorw(ctx, "[Database] query failed", append(fields(sql, rows), "error", err.Error())...)
+	case g.slowThreshold > 0 && elapsed > g.slowThreshold && g.level >= gormlogger.Warn:
+		sql, rows := fc()
+		g.l.Warnw(ctx, fmt.Sprintf("[Database] slow query over %s", g.slowThreshold), fields(sql, rows)...)
+	case g.level >= gormlogger.Info:
+		sql, rows := fc()
+		g.l.Infow(ctx, "[Database] query", fields(sql, rows)...)
+	}
+}
+
+// ParamsFilter leaves the parameters out of the logged SQL when they are redacted, GORM logs placeholders instead.
+func (g *gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if g.redactParams {
+		return sql, nil
+	}
+	return sql, params
+}
+
+// gormLoggerFromConfig returns the GORM logger of the connection name, GORM only logs errors
+// to stdout when ErrorLog is set and logs nothing otherwise unless LogEnabled is set.
+func gormLoggerFromConfig(name string, cfg *Config) (gormlogger.Interface, error) {
+	if cfg.LogEnabled {
+		return newGormLogger(logger.GetProvider().Logger(), name, cfg)
+	}
+	if !cfg.ErrorLog {
+		return gormlogger.Default.LogMode(gormlogger.Silent), nil
+	}
+	return gormlogger.Default, nil
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/saigontechnology/go-shared-packages/logger"
+)
+
+type logEntry struct {
+	level  string
+	msg    string
+	fields map[string]interface{}
+}
+
+type loggerForTest struct {
+	*logger.NoopLogger
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (l *loggerForTest) log(level, msg string, keysAndValues []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fields := make(map[string]interface{})
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fields[keysAndValues[i].(string)] = keysAndValues[i+1]
+	}
+	l.entries = append(l.entries, logEntry{level: level, msg: msg, fields: fields})
+}
+
+func (l *loggerForTest) Infow(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.log("info", msg, keysAndValues)
+}
+
+func (l *loggerForTest) Warnw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.log("warn", msg, keysAndValues)
+}
+
+func (l *loggerForTest) Errorw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.log("error", msg, keysAndValues)
+}
+
+func TestGormLogger(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		cfg       Config
+		wantLevel []string
+		wantSQL   string
+	}{
+		{
+			name:      "Info logs all queries with redacted parameters",
+			cfg:       Config{LogLevel: "info", SlowQueryThreshold: time.Hour, LogRedactParams: true},
+			wantLevel: []string{"info", "info", "error"},
+			wantSQL:   "SELECT * FROM `pets` WHERE name = ?",
+		},
+		{
+			name:      "Info logs parameters when they are not redacted",
+			cfg:       Config{LogLevel: "info", SlowQueryThreshold: time.Hour},
+			wantLevel: []string{"info", "info", "error"},
+			wantSQL:   "SELECT * FROM `pets` WHERE name = \"Milo\"",
+		},
+		{
+			name:      "Warn logs slow queries",
+			cfg:       Config{LogLevel: "warn", SlowQueryThreshold: time.Nanosecond, LogRedactParams: true},
+			wantLevel: []string{"warn", "warn", "error"},
+			wantSQL:   "SELECT * FROM `pets` WHERE name = ?",
+		},
+		{
+			name:      "Error only logs failed queries",
+			cfg:       Config{LogLevel: "error", SlowQueryThreshold: time.Nanosecond},
+			wantLevel: []string{"error"},
+		},
+		{
+			name: "Silent logs nothing",
+			cfg:  Config{LogLevel: "silent"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			l := &loggerForTest{}
+			gormLogger, err := newGormLogger(l, "main", &tc.cfg)
+			r.NoError(err)
+			db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: gormLogger})
+			r.NoError(err)
+			r.NoError(db.Exec("CREATE TABLE pets (id INTEGER PRIMARY KEY, name TEXT)").Error)
+			l.entries = nil
+
+			r.NoError(db.Where("name = ?", "Milo").Find(&[]pet{}).Error)
+			r.ErrorIs(db.First(&pet{}, 42).Error, gorm.ErrRecordNotFound)
+			r.Error(db.Exec("SELECT * FROM missing").Error)
+
+			var levels []string
+			for _, entry := range l.entries {
+				levels = append(levels, entry.level)
+				r.Equal("main", entry.fields["db"])
+			}
+			r.Equal(tc.wantLevel, levels)
+			if tc.wantSQL != "" {
+				r.Equal(tc.wantSQL, l.entries[0].fields["sql"])
+			}
+		})
+	}
+}
+
+func TestNewGormLogger_UnsupportedLevel(t *testing.T) {
+	t.Parallel()
+	_, err := newGormLogger(&loggerForTest{}, "main", &Config{LogLevel: "debug"})
+	require.Error(t, err)
+}
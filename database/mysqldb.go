@@ -1,16 +1,19 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
-	"gorm.io/plugin/dbresolver"
 
 	"github.com/saigontechnology/go-shared-packages/must"
+	"github.com/saigontechnology/go-shared-packages/prometheus"
 
 	_ "github.com/newrelic/go-agent/v3/integrations/nrmysql"
 )
@@ -21,21 +24,33 @@ var (
 )
 
 type mysqlDB struct {
-	mu  sync.Mutex
-	dbs map[string]*gorm.DB
+	mu      sync.Mutex
+	dbs     map[string]*gorm.DB
+	configs configs
 }
 
 // GetMysqlDB singleton implementation makes sure only one mysqlDB is created to avoid duplicated database connection pools.
 func GetMysqlDB() Connector {
 	mysqlDBOnce.Do(func() {
-		mysqlDBInstance = &mysqlDB{
-			dbs: make(map[string]*gorm.DB),
-		}
+		mysqlDBInstance = newMysqlDB(configs{})
 	})
 
 	return mysqlDBInstance
 }
 
+// NewMysqlDB creates a Connector with its own connection pools, connections are configured with WithConfig
+// or from the environment.
+func NewMysqlDB(opts ...Option) Connector {
+	return newMysqlDB(newOptions(opts...).configs)
+}
+
+func newMysqlDB(c configs) *mysqlDB {
+	return &mysqlDB{
+		dbs:     make(map[string]*gorm.DB),
+		configs: c,
+	}
+}
+
 func (m *mysqlDB) Connect(name string) *gorm.DB {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -44,22 +59,24 @@ func (m *mysqlDB) Connect(name string) *gorm.DB {
 		return m.dbs[name]
 	}
 
-	cfg, err := newConfig(name)
+	cfg, err := m.configs.get(name)
 	must.NotFail(err)
 	// Setting up gorm config
 	gormConfig := gorm.Config{
 		// We should monitor service performance first then decide whether we disable default transaction or not
 		// SkipDefaultTransaction: true,
 	}
-	if !cfg.ErrorLog {
-		gormConfig.Logger = logger.Default.LogMode(logger.Silent)
-	}
+	gormConfig.Logger, err = gormLoggerFromConfig(name, cfg)
+	must.NotFail(err)
 
 	gormDB, err := gorm.Open(
 		mysql.New(mysql.Config{DriverName: "nrmysql", DSN: m.dsnFromConfig(cfg)}),
 		&gormConfig,
 	)
 	must.NotFail(err)
+	// nrmysql already opens New Relic segments for MySQL queries
+	err = gormDB.Use(newMetricsPlugin(name, cfg, false))
+	must.NotFail(err)
 	sqlDB, err := gormDB.DB()
 	must.NotFail(err)
 	// SetMaxIdleConns sets the maximum number of connections in the idle connection pool.
@@ -68,30 +85,87 @@ func (m *mysqlDB) Connect(name string) *gorm.DB {
 	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
 	// SetConnMaxLifetime sets the maximum amount of time a connection may be reused.
 	sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime))
+	prometheus.GetDBMetric().AddDB(name, sqlDB)
 
 	m.dbs[name] = gormDB
 	return m.dbs[name]
 }
 
 func (m *mysqlDB) SetReplicas(masterDB *gorm.DB, names []string) {
-	dialectors := make([]gorm.Dialector, len(names))
-	for i, name := range names {
-		cfg, err := newConfig(name)
-		must.NotFail(err)
-		dialectors[i] = mysql.New(mysql.Config{DriverName: "nrmysql", DSN: m.dsnFromConfig(cfg)})
+	m.mu.Lock()
+	name := connectionName(m.dbs, masterDB)
+	m.mu.Unlock()
+	cfg, err := m.configs.get(name)
+	must.NotFail(err)
+	err = setReplicas(m, name, cfg, masterDB, names, m.configs)
+	must.NotFail(err)
+}
+
+func (m *mysqlDB) openReplica(cfg *Config) (*sql.DB, error) {
+	return sql.Open("nrmysql", m.dsnFromConfig(cfg))
+}
+
+func (m *mysqlDB) replicaDialector(conn *sql.DB) gorm.Dialector {
+	return mysql.New(mysql.Config{Conn: conn})
+}
+
+// replicationLag reads Seconds_Behind_Source with SHOW REPLICA STATUS, which exists since MySQL 8.0.22.
+// On older servers it falls back to Seconds_Behind_Master of SHOW SLAVE STATUS.
+// Both statements need the REPLICATION CLIENT privilege, so the user of the replicas must be granted it
+// when DB_REPLICA_MAX_LAG is set, otherwise every replica is removed from rotation.
+func (m *mysqlDB) replicationLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	lag, err := replicationStatusLag(ctx, db, "SHOW REPLICA STATUS")
+	if err == nil || ctx.Err() != nil {
+		return lag, err
 	}
+	lag, slaveErr := replicationStatusLag(ctx, db, "SHOW SLAVE STATUS")
+	if slaveErr != nil {
+		return 0, errors.Join(err, slaveErr)
+	}
+	return lag, nil
+}
 
-	err := masterDB.Use(dbresolver.Register(dbresolver.Config{
-		Replicas: dialectors,
-		// sources/replicas load balancing policy
-		Policy: dbresolver.RandomPolicy{},
-		// print sources/replicas mode in logger
-		TraceResolverMode: true,
-	}))
-	must.NotFail(err)
+func replicationStatusLag(ctx context.Context, db *sql.DB, query string) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, errors.New("[Database] replication is not configured")
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return 0, err
+	}
+	for i, column := range columns {
+		if column != "Seconds_Behind_Source" && column != "Seconds_Behind_Master" {
+			continue
+		}
+		if !values[i].Valid {
+			return 0, errors.New("[Database] replication is not running")
+		}
+		seconds, err := strconv.ParseInt(values[i].String, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return 0, errors.New("[Database] replication lag is not reported")
 }
 
-func (m *mysqlDB) dsnFromConfig(cfg *config) string {
+func (m *mysqlDB) dsnFromConfig(cfg *Config) string {
 	return fmt.Sprintf(
 		"%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=True&loc=Local",
 		cfg.Username,
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"gorm.io/gorm"
+
+	"github.com/saigontechnology/go-shared-packages/prometheus"
+)
+
+const (
+	metricsPluginName = "metrics"
+
+	metricsStartTimeKey = "metrics:start_time"
+	metricsSegmentKey   = "metrics:segment"
+
+	unknownTable = "unknown"
+)
+
+// metricsPlugin records the latency, errors and rows of every query run through GORM,
+// so repositories do not need to call prometheus.InsideLatencyMetric themselves.
+// For Postgres it also opens New Relic datastore segments, MySQL queries are already traced by nrmysql.
+type metricsPlugin struct {
+	name     string
+	metric   prometheus.DBMetric
+	segments bool
+	host     string
+	port     string
+	dbName   string
+}
+
+func newMetricsPlugin(name string, cfg *Config, segments bool) *metricsPlugin {
+	return &metricsPlugin{
+		name:     name,
+		metric:   prometheus.GetDBMetric(),
+		segments: segments,
+		host:     cfg.Host,
+		port:     cfg.Port,
+		dbName:   cfg.Name,
+	}
+}
+
+func (p *metricsPlugin) Name() string {
+	return metricsPluginName
+}
+
+func (p *metricsPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("gorm:create").Register("metrics:before_create", p.before("create")),
+		callback.Create().After("gorm:create").Register("metrics:after_create", p.after("create")),
+		callback.Query().Before("gorm:query").Register("metrics:before_query", p.before("query")),
+		callback.Query().After("gorm:query").Register("metrics:after_query", p.after("query")),
+		callback.Update().Before("gorm:update").Register("metrics:before_update", p.before("update")),
+		callback.Update().After("gorm:update").Register("metrics:after_update", p.after("update")),
+		callback.Delete().Before("gorm:delete").Register("metrics:before_delete", p.before("delete")),
+		callback.Delete().After("gorm:delete").Register("metrics:after_delete", p.after("delete")),
+		callback.Row().Before("gorm:row").Register("metrics:before_row", p.before("row")),
+		callback.Row().After("gorm:row").Register("metrics:after_row", p.after("row")),
+		callback.Raw().Before("gorm:raw").Register("metrics:before_raw", p.before("raw")),
+		callback.Raw().After("gorm:raw").Register("metrics:after_raw", p.after("raw")),
+	)
+}
+
+func (p *metricsPlugin) before(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		db.InstanceSet(metricsStartTimeKey, time.Now())
+		if !p.segments || db.Statement.Context == nil {
+			return
+		}
+		txn := newrelic.FromContext(db.Statement.Context)
+		if txn == nil {
+			return
+		}
+		db.InstanceSet(metricsSegmentKey, &newrelic.DatastoreSegment{
+			StartTime:    txn.StartSegmentNow(),
+			Product:      newrelic.DatastorePostgres,
+			Collection:   table(db),
+			Operation:    strings.ToUpper(operation),
+			Host:         p.host,
+			PortPathOrID: p.port,
+			DatabaseName: p.dbName,
+		})
+	}
+}
+
+func (p *metricsPlugin) after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if value, ok := db.InstanceGet(metricsSegmentKey); ok {
+			if s, ok := value.(*newrelic.DatastoreSegment); ok {
+				s.ParameterizedQuery = db.Statement.SQL.String()
+				s.End()
+			}
+		}
+		value, ok := db.InstanceGet(metricsStartTimeKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		err := db.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// A missing record is an expected result, not a failure of the database
+			err = nil
+		}
+		p.metric.ObserveQuery(p.name, table(db), operation, time.Since(start), db.RowsAffected, err)
+	}
+}
+
+func table(db *gorm.DB) string {
+	if db.Statement.Table != "" {
+		return db.Statement.Table
+	}
+	if db.Statement.Schema != nil {
+		return db.Statement.Schema.Table
+	}
+	return unknownTable
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type observedQuery struct {
+	name      string
+	table     string
+	operation string
+	rows      int64
+	err       error
+}
+
+type dbMetricForTest struct {
+	mu      sync.Mutex
+	queries []observedQuery
+	active  map[string]bool
+}
+
+func (m *dbMetricForTest) AddDB(name string, db *sql.DB) {}
+
+func (m *dbMetricForTest) RemoveDB(db *sql.DB) {}
+
+func (m *dbMetricForTest) ObserveQuery(name, table, operation string, duration time.Duration, rows int64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queries = append(m.queries, observedQuery{name: name, table: table, operation: operation, rows: rows, err: err})
+}
+
+func (m *dbMetricForTest) SetReplicaActive(name, replica string, active bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.active == nil {
+		m.active = make(map[string]bool)
+	}
+	m.active[replica] = active
+}
+
+func (m *dbMetricForTest) SetReplicaLag(name, replica string, lag time.Duration) {}
+
+func (m *dbMetricForTest) RemoveReplica(name, replica string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.active, replica)
+}
+
+type pet struct {
+	ID   int
+	Name string
+}
+
+func TestMetricsPlugin(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	r.NoError(err)
+	r.NoError(db.AutoMigrate(&pet{}))
+	metric := &dbMetricForTest{}
+	plugin := newMetricsPlugin("main", &Config{}, true)
+	plugin.metric = metric
+	r.NoError(db.Use(plugin))
+
+	r.NoError(db.Create(&[]pet{{Name: "Milo"}, {Name: "Luna"}}).Error)
+	r.NoError(db.Model(&pet{}).Where("name = ?", "Milo").Update("name", "Max").Error)
+	r.ErrorIs(db.First(&pet{}, 42).Error, gorm.ErrRecordNotFound)
+	r.Error(db.Exec("SELECT * FROM missing").Error)
+
+	r.Len(metric.queries, 4)
+	// A missing record is not counted as an error
+	r.Equal([]observedQuery{
+		{name: "main", table: "pets", operation: "create", rows: 2},
+		{name: "main", table: "pets", operation: "update", rows: 1},
+		{name: "main", table: "pets", operation: "query"},
+	}, metric.queries[:3])
+	r.Equal("unknown", metric.queries[3].table)
+	r.Equal("raw", metric.queries[3].operation)
+	r.Error(metric.queries[3].err)
+}
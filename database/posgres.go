@@ -1,16 +1,19 @@
 package database
 
 import (
-	"fmt"
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
-	"gorm.io/plugin/dbresolver"
 
 	"github.com/saigontechnology/go-shared-packages/must"
+	"github.com/saigontechnology/go-shared-packages/prometheus"
 )
 
 var (
@@ -19,21 +22,33 @@ var (
 )
 
 type postgresDB struct {
-	mu  sync.Mutex
-	dbs map[string]*gorm.DB
+	mu      sync.Mutex
+	dbs     map[string]*gorm.DB
+	configs configs
 }
 
 // GetPostgresDB singleton implementation makes sure only one postgresDB is created to avoid duplicated database connection pools.
 func GetPostgresDB() Connector {
 	postgresDBOnce.Do(func() {
-		postgresDBInstance = &postgresDB{
-			dbs: make(map[string]*gorm.DB),
-		}
+		postgresDBInstance = newPostgresDB(configs{})
 	})
 
 	return postgresDBInstance
 }
 
+// NewPostgresDB creates a Connector with its own connection pools, connections are configured with WithConfig
+// or from the environment.
+func NewPostgresDB(opts ...Option) Connector {
+	return newPostgresDB(newOptions(opts...).configs)
+}
+
+func newPostgresDB(c configs) *postgresDB {
+	return &postgresDB{
+		dbs:     make(map[string]*gorm.DB),
+		configs: c,
+	}
+}
+
 func (m *postgresDB) Connect(name string) *gorm.DB {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -42,22 +57,23 @@ func (m *postgresDB) Connect(name string) *gorm.DB {
 		return m.dbs[name]
 	}
 
-	cfg, err := newConfig(name)
+	cfg, err := m.configs.get(name)
 	must.NotFail(err)
 	// Setting up gorm config
 	gormConfig := gorm.Config{
 		// We should monitor service performance first then decide whether we disable default transaction or not
 		// SkipDefaultTransaction: true,
 	}
-	if !cfg.ErrorLog {
-		gormConfig.Logger = logger.Default.LogMode(logger.Silent)
-	}
+	gormConfig.Logger, err = gormLoggerFromConfig(name, cfg)
+	must.NotFail(err)
 
 	gormDB, err := gorm.Open(
 		postgres.New(postgres.Config{DriverName: "pgx", DSN: m.dsnFromConfig(cfg)}),
 		&gormConfig,
 	)
 	must.NotFail(err)
+	err = gormDB.Use(newMetricsPlugin(name, cfg, true))
+	must.NotFail(err)
 	sqlDB, err := gormDB.DB()
 	must.NotFail(err)
 	// SetMaxIdleConns sets the maximum number of connections in the idle connection pool.
@@ -66,36 +82,93 @@ func (m *postgresDB) Connect(name string) *gorm.DB {
 	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
 	// SetConnMaxLifetime sets the maximum amount of time a connection may be reused.
 	sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime))
+	prometheus.GetDBMetric().AddDB(name, sqlDB)
 
 	m.dbs[name] = gormDB
 	return m.dbs[name]
 }
 
 func (m *postgresDB) SetReplicas(masterDB *gorm.DB, names []string) {
-	dialectors := make([]gorm.Dialector, len(names))
-	for i, name := range names {
-		cfg, err := newConfig(name)
-		must.NotFail(err)
-		dialectors[i] = postgres.New(postgres.Config{DriverName: "pgx", DSN: m.dsnFromConfig(cfg)})
+	m.mu.Lock()
+	name := connectionName(m.dbs, masterDB)
+	m.mu.Unlock()
+	cfg, err := m.configs.get(name)
+	must.NotFail(err)
+	err = setReplicas(m, name, cfg, masterDB, names, m.configs)
+	must.NotFail(err)
+}
+
+func (m *postgresDB) openReplica(cfg *Config) (*sql.DB, error) {
+	return sql.Open("pgx", m.dsnFromConfig(cfg))
+}
+
+func (m *postgresDB) replicaDialector(conn *sql.DB) gorm.Dialector {
+	return postgres.New(postgres.Config{Conn: conn})
+}
+
+// replicationLag is 0 when the replica replayed all it received, otherwise it is the age of the last replayed
+// transaction. It is 0 on a server which is not a replica.
+func (m *postgresDB) replicationLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	var seconds float64
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(CASE
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+END, 0)`).Scan(&seconds)
+	if err != nil {
+		return 0, err
 	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
 
-	err := masterDB.Use(dbresolver.Register(dbresolver.Config{
-		Replicas: dialectors,
-		// sources/replicas load balancing policy
-		Policy: dbresolver.RandomPolicy{},
-		// print sources/replicas mode in logger
-		TraceResolverMode: true,
-	}))
-	must.NotFail(err)
+func (m *postgresDB) dsnFromConfig(cfg *Config) string {
+	return PostgresDSN(cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.Name, cfg.PostgresParams)
 }
 
-func (m *postgresDB) dsnFromConfig(cfg *config) string {
-	return fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Ho_Chi_Minh",
-		cfg.Host,
-		cfg.Username,
-		cfg.Password,
-		cfg.Name,
-		cfg.Port,
-	)
+// PostgresDSN builds a key/value connection string, optional parameters are left out when they are empty.
+func PostgresDSN(host, port, username, password, name string, params PostgresParams) string {
+	dsn := []string{
+		dsnPair("host", host),
+		dsnPair("user", username),
+		dsnPair("password", password),
+		dsnPair("dbname", name),
+		dsnPair("port", port),
+	}
+	optional := [][2]string{
+		{"sslmode", params.SSLMode},
+		{"sslrootcert", params.SSLRootCert},
+		{"sslcert", params.SSLCert},
+		{"sslkey", params.SSLKey},
+		{"TimeZone", params.TimeZone},
+		{"search_path", params.SearchPath},
+		{"application_name", params.ApplicationName},
+	}
+	if params.StatementTimeout > 0 {
+		// Postgres reads the timeout in milliseconds
+		timeout := strconv.FormatInt(params.StatementTimeout.Milliseconds(), 10)
+		optional = append(optional, [2]string{"statement_timeout", timeout})
+	}
+	extraKeys := make([]string, 0, len(params.ExtraParams))
+	for key := range params.ExtraParams {
+		extraKeys = append(extraKeys, key)
+	}
+	sort.Strings(extraKeys)
+	for _, key := range extraKeys {
+		optional = append(optional, [2]string{key, params.ExtraParams[key]})
+	}
+	for _, pair := range optional {
+		if pair[1] != "" {
+			dsn = append(dsn, dsnPair(pair[0], pair[1]))
+		}
+	}
+	return strings.Join(dsn, " ")
+}
+
+// dsnPair quotes values which are empty or contain spaces, quotes or backslashes.
+func dsnPair(key, value string) string {
+	if value == "" || strings.ContainsAny(value, ` '\`) {
+		value = strings.ReplaceAll(value, `\`, `\\`)
+		value = strings.ReplaceAll(value, `'`, `\'`)
+		value = "'" + value + "'"
+	}
+	return key + "=" + value
 }
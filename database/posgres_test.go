@@ -0,0 +1,86 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/saigontechnology/go-shared-packages/database"
+)
+
+func TestPostgresDSN(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		password string
+		params   database.PostgresParams
+		want     string
+	}{
+		{
+			name:     "Defaults",
+			password: "secret",
+			params:   database.PostgresParams{SSLMode: "disable", TimeZone: "Asia/Ho_Chi_Minh"},
+			want:     "host=db user=app password=secret dbname=app port=5432 sslmode=disable TimeZone=Asia/Ho_Chi_Minh",
+		},
+		{
+			name:     "TLS",
+			password: "secret",
+			params: database.PostgresParams{
+				SSLMode:     "verify-full",
+				SSLRootCert: "/certs/ca.pem",
+				SSLCert:     "/certs/client.pem",
+				SSLKey:      "/certs/client.key",
+				TimeZone:    "UTC",
+			},
+			want: "host=db user=app password=secret dbname=app port=5432 sslmode=verify-full " +
+				"sslrootcert=/certs/ca.pem sslcert=/certs/client.pem sslkey=/certs/client.key TimeZone=UTC",
+		},
+		{
+			name:     "Session parameters",
+			password: "secret",
+			params: database.PostgresParams{
+				SearchPath:       "tenant,public",
+				ApplicationName:  "billing api",
+				StatementTimeout: 30 * time.Second,
+				ExtraParams:      map[string]string{"target_session_attrs": "read-write", "connect_timeout": "5"},
+			},
+			want: "host=db user=app password=secret dbname=app port=5432 search_path=tenant,public " +
+				"application_name='billing api' statement_timeout=30000 connect_timeout=5 target_session_attrs=read-write",
+		},
+		{
+			name:     "Quoted password",
+			password: `it's a \secret`,
+			want:     `host=db user=app password='it\'s a \\secret' dbname=app port=5432`,
+		},
+		{
+			name: "Empty password",
+			want: "host=db user=app password='' dbname=app port=5432",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.want, database.PostgresDSN("db", "5432", "app", tc.password, "app", tc.params))
+		})
+	}
+}
+
+func TestNewConfig_PostgresParams(t *testing.T) {
+	t.Setenv("DB_SSL_MODE", "require")
+	t.Setenv("REPORT_DB_SSL_MODE", "verify-full")
+	t.Setenv("REPORT_DB_EXTRA_PARAMS", "connect_timeout:5")
+
+	cfg, err := database.NewConfig("main")
+	require.NoError(t, err)
+	require.Equal(t, "require", cfg.SSLMode)
+	require.Equal(t, "Asia/Ho_Chi_Minh", cfg.TimeZone)
+
+	cfg, err = database.NewConfig("report")
+	require.NoError(t, err)
+	require.Equal(t, "verify-full", cfg.SSLMode)
+	require.Equal(t, map[string]string{"connect_timeout": "5"}, cfg.ExtraParams)
+}
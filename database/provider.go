@@ -8,6 +8,7 @@ import (
 
 	"github.com/saigontechnology/go-shared-packages/env"
 	"github.com/saigontechnology/go-shared-packages/must"
+	"github.com/saigontechnology/go-shared-packages/prometheus"
 )
 
 var (
@@ -27,9 +28,14 @@ type Provider interface {
 }
 
 // CloseDB closes database connection pool before exiting the main function.
+// The replicas set with SetReplicas are closed with it.
 func CloseDB(db *gorm.DB) {
 	sqlDB, err := db.DB()
 	must.NotFail(err)
+	if err = closeReplicaSet(sqlDB); err != nil {
+		log.Printf("[Database] could not close replica connection pools. Error: %s", err.Error())
+	}
+	prometheus.GetDBMetric().RemoveDB(sqlDB)
 	if err = sqlDB.Close(); err != nil {
 		log.Println("!!! API could not close database connection pool")
 	}
@@ -37,24 +43,43 @@ func CloseDB(db *gorm.DB) {
 
 type provider struct {
 	connector Connector
+	configs   configs
 }
 
 // GetProvider singleton implementation makes sure only one Provider is created to avoid duplicated database connection pools.
 func GetProvider() Provider {
 	providerOnce.Do(func() {
-		providerInstance = &provider{}
+		providerInstance = newProvider()
 	})
 
 	return providerInstance
 }
 
+// New creates a Provider, the connections configured with WithConfig get their own MySQL connection pools
+// unless a Connector is given with WithConnector.
+func New(opts ...Option) Provider {
+	return newProvider(opts...)
+}
+
+func newProvider(opts ...Option) *provider {
+	o := newOptions(opts...)
+	return &provider{
+		connector: o.connector,
+		configs:   o.configs,
+	}
+}
+
 func (p *provider) SetConnector(c Connector) Provider {
 	p.connector = c
 	return p
 }
 
 func (p *provider) setDefaultConnector() {
-	p.connector = GetMysqlDB()
+	if len(p.configs) == 0 {
+		p.connector = GetMysqlDB()
+		return
+	}
+	p.connector = newMysqlDB(p.configs)
 }
 
 func (p *provider) DB(name string) *gorm.DB {
@@ -70,6 +95,10 @@ func (p *provider) DB(name string) *gorm.DB {
 func (p *provider) SetReplicas(masterDB *gorm.DB, names []string) {
 	if env.IsTestEnv() {
 		GetDumpDB().SetReplicas(masterDB, names)
+		return
+	}
+	if p.connector == nil {
+		p.setDefaultConnector()
 	}
 	p.connector.SetReplicas(masterDB, names)
 }
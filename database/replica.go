@@ -0,0 +1,326 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+
+	"github.com/saigontechnology/go-shared-packages/prometheus"
+)
+
+const (
+	ReplicaPolicyRandom           = "random"
+	ReplicaPolicyRoundRobin       = "round-robin"
+	ReplicaPolicyWeighted         = "weighted"
+	ReplicaPolicyLeastConnections = "least-connections"
+)
+
+// replicaSets are the replica sets by the connection pool of their master, so they are closed with it.
+var replicaSets sync.Map
+
+// replicaDriver opens the replicas of a connector.
+type replicaDriver interface {
+	openReplica(cfg *Config) (*sql.DB, error)
+	replicaDialector(conn *sql.DB) gorm.Dialector
+	// replicationLag returns how far the replica is behind its master
+	replicationLag(ctx context.Context, db *sql.DB) (time.Duration, error)
+}
+
+type replica struct {
+	name    string
+	db      *sql.DB
+	weight  int
+	healthy atomic.Bool
+}
+
+// replicaSet is the dbresolver.Policy of a connection. It only balances reads between healthy replicas,
+// reads go to the master while all replicas are unhealthy.
+type replicaSet struct {
+	name     string
+	policy   string
+	replicas []*replica
+	master   gorm.ConnPool
+	driver   replicaDriver
+	metric   prometheus.DBMetric
+
+	checkInterval time.Duration
+	pingTimeout   time.Duration
+	maxLag        time.Duration
+
+	next atomic.Uint64
+	mu   sync.Mutex
+	rand *rand.Rand
+
+	// cancel stops the health checks, done is closed once they stopped
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newReplicaSet(
+	name string,
+	cfg *Config,
+	master gorm.ConnPool,
+	replicas []*replica,
+	driver replicaDriver,
+) (*replicaSet, error) {
+	switch cfg.ReplicaPolicy {
+	case ReplicaPolicyRandom, ReplicaPolicyRoundRobin, ReplicaPolicyLeastConnections:
+	case ReplicaPolicyWeighted:
+		for _, r := range replicas {
+			if r.weight <= 0 {
+				return nil, fmt.Errorf("[Database] weight of replica %s must be positive, got %d", r.name, r.weight)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("[Database] replica policy %s is not supported", cfg.ReplicaPolicy)
+	}
+	s := &replicaSet{
+		name:          name,
+		policy:        cfg.ReplicaPolicy,
+		replicas:      replicas,
+		master:        master,
+		driver:        driver,
+		metric:        prometheus.GetDBMetric(),
+		checkInterval: cfg.ReplicaCheckInterval,
+		pingTimeout:   cfg.ReplicaPingTimeout,
+		maxLag:        cfg.ReplicaMaxLag,
+		//nolint: gosec
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, r := range replicas {
+		r.healthy.Store(true)
+		s.metric.SetReplicaActive(name, r.name, true)
+	}
+	return s, nil
+}
+
+// setReplicas opens the replicas of masterDB with their own connection pools and balances reads between them.
+// The replicas are closed by CloseDB with the master, the pools already opened are closed when it fails.
+// Replicas are set once per master, a second call fails until the master is closed.
+func setReplicas(
+	driver replicaDriver,
+	name string,
+	cfg *Config,
+	masterDB *gorm.DB,
+	replicaNames []string,
+	configs configs,
+) (err error) {
+	masterSQLDB, err := masterDB.DB()
+	if err != nil {
+		return err
+	}
+	if _, ok := replicaSets.Load(masterSQLDB); ok {
+		return fmt.Errorf("[Database] replicas of %s are already set", name)
+	}
+	replicas := make([]*replica, 0, len(replicaNames))
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, closeReplicas(prometheus.GetDBMetric(), name, replicas))
+		}
+	}()
+	dialectors := make([]gorm.Dialector, 0, len(replicaNames)+1)
+	for _, replicaName := range replicaNames {
+		replicaCfg, err := configs.get(replicaName)
+		if err != nil {
+			return err
+		}
+		db, err := driver.openReplica(replicaCfg)
+		if err != nil {
+			return err
+		}
+		db.SetMaxIdleConns(replicaCfg.MaxIdleConns)
+		db.SetMaxOpenConns(replicaCfg.MaxOpenConns)
+		db.SetConnMaxLifetime(time.Duration(replicaCfg.ConnMaxLifetime))
+		prometheus.GetDBMetric().AddDB(replicaName, db)
+		replicas = append(replicas, &replica{name: replicaName, db: db, weight: replicaCfg.ReplicaWeight})
+		dialectors = append(dialectors, driver.replicaDialector(db))
+	}
+	set, err := newReplicaSet(name, cfg, masterDB.Config.ConnPool, replicas, driver)
+	if err != nil {
+		return err
+	}
+	if len(dialectors) == 1 {
+		// dbresolver does not call the policy when there is only one replica,
+		// the master is added so a single unhealthy replica can still be left out
+		dialectors = append(dialectors, driver.replicaDialector(masterSQLDB))
+	}
+
+	err = masterDB.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: dialectors,
+		// sources/replicas load balancing policy
+		Policy: set,
+		// print sources/replicas mode in logger
+		TraceResolverMode: true,
+	}))
+	if err != nil {
+		return err
+	}
+	if _, loaded := replicaSets.LoadOrStore(masterSQLDB, set); loaded {
+		return fmt.Errorf("[Database] replicas of %s are already set", name)
+	}
+	if set.checkInterval > 0 {
+		set.start()
+	}
+	return nil
+}
+
+// closeReplicaSet closes the replicas of the master pool, if it has any.
+func closeReplicaSet(master *sql.DB) error {
+	set, ok := replicaSets.LoadAndDelete(master)
+	if !ok {
+		return nil
+	}
+	return set.(*replicaSet).close()
+}
+
+// closeReplicas closes the connection pools of the replicas of the database name and removes their metrics.
+func closeReplicas(metric prometheus.DBMetric, name string, replicas []*replica) error {
+	errs := make([]error, 0, len(replicas))
+	for _, r := range replicas {
+		metric.RemoveReplica(name, r.name)
+		metric.RemoveDB(r.db)
+		errs = append(errs, r.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Resolve ignores the pools of dbresolver, they are the replicas of the set and the master.
+func (s *replicaSet) Resolve(_ []gorm.ConnPool) gorm.ConnPool {
+	healthy := make([]*replica, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		if r.healthy.Load() {
+			healthy = append(healthy, r)
+		}
+	}
+	if len(healthy) == 0 {
+		return s.master
+	}
+
+	switch s.policy {
+	case ReplicaPolicyRoundRobin:
+		return healthy[(s.next.Add(1)-1)%uint64(len(healthy))].db
+	case ReplicaPolicyWeighted:
+		total := 0
+		for _, r := range healthy {
+			total += r.weight
+		}
+		pick := s.intn(total)
+		for _, r := range healthy {
+			if pick < r.weight {
+				return r.db
+			}
+			pick -= r.weight
+		}
+		return healthy[len(healthy)-1].db
+	case ReplicaPolicyLeastConnections:
+		least := healthy[0]
+		for _, r := range healthy[1:] {
+			if r.db.Stats().InUse < least.db.Stats().InUse {
+				least = r
+			}
+		}
+		return least.db
+	default:
+		return healthy[s.intn(len(healthy))].db
+	}
+}
+
+// intn is safe for concurrent use, unlike rand.Rand.
+func (s *replicaSet) intn(n int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rand.Intn(n)
+}
+
+// start runs the health checks until close is called.
+func (s *replicaSet) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		s.run(ctx)
+	}()
+}
+
+// close stops the health checks, closes the connection pools of the replicas and removes their metrics.
+func (s *replicaSet) close() error {
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+	}
+	return closeReplicas(s.metric, s.name, s.replicas)
+}
+
+func (s *replicaSet) run(ctx context.Context) {
+	ticker := time.NewTicker(s.checkInterval)
+	defer ticker.Stop()
+	for {
+		s.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check removes the unhealthy replicas from rotation and adds back the ones which recovered.
+func (s *replicaSet) check(ctx context.Context) {
+	for _, r := range s.replicas {
+		err := s.checkReplica(ctx, r)
+		if ctx.Err() != nil {
+			// The replica set is being closed
+			return
+		}
+		healthy := err == nil
+		if r.healthy.Swap(healthy) == healthy {
+			continue
+		}
+		s.metric.SetReplicaActive(s.name, r.name, healthy)
+		if healthy {
+			log.Printf("[Database] replica %s of %s is healthy again, it is added back to rotation", r.name, s.name)
+		} else {
+			log.Printf("[Database] replica %s of %s is removed from rotation. Error: %s", r.name, s.name, err.Error())
+		}
+	}
+}
+
+func (s *replicaSet) checkReplica(ctx context.Context, r *replica) error {
+	ctx, cancel := context.WithTimeout(ctx, s.pingTimeout)
+	defer cancel()
+	if err := r.db.PingContext(ctx); err != nil {
+		return err
+	}
+	if s.maxLag <= 0 {
+		return nil
+	}
+	lag, err := s.driver.replicationLag(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	s.metric.SetReplicaLag(s.name, r.name, lag)
+	if lag > s.maxLag {
+		return fmt.Errorf("[Database] replication lag %s is over %s", lag, s.maxLag)
+	}
+	return nil
+}
+
+// connectionName returns the name db was connected with, an empty name when it was not connected by the connector.
+func connectionName(dbs map[string]*gorm.DB, db *gorm.DB) string {
+	for name, connected := range dbs {
+		if connected == db {
+			return name
+		}
+	}
+	return ""
+}
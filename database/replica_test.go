@@ -0,0 +1,290 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type replicaDriverForTest struct {
+	mu     sync.Mutex
+	lag    map[*sql.DB]time.Duration
+	opened []*sql.DB
+}
+
+func (d *replicaDriverForTest) openReplica(cfg *Config) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", cfg.Name)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.opened = append(d.opened, db)
+	return db, nil
+}
+
+func (d *replicaDriverForTest) replicaDialector(conn *sql.DB) gorm.Dialector {
+	return &sqlite.Dialector{Conn: conn}
+}
+
+func (d *replicaDriverForTest) replicationLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.lag[db], nil
+}
+
+func newReplicasForTest(t *testing.T, weights ...int) []*replica {
+	t.Helper()
+	replicas := make([]*replica, len(weights))
+	for i, weight := range weights {
+		db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "replica.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		replicas[i] = &replica{name: string(rune('a' + i)), db: db, weight: weight}
+	}
+	return replicas
+}
+
+func newReplicaSetForTest(t *testing.T, policy string, replicas []*replica) (*replicaSet, *sql.DB) {
+	t.Helper()
+	master, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "master.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		master.Close()
+	})
+	set, err := newReplicaSet("main", &Config{
+		ReplicaPolicy:      policy,
+		ReplicaPingTimeout: time.Second,
+	}, master, replicas, &replicaDriverForTest{})
+	require.NoError(t, err)
+	set.metric = &dbMetricForTest{}
+	return set, master
+}
+
+func countResolved(set *replicaSet, times int) map[gorm.ConnPool]int {
+	counts := make(map[gorm.ConnPool]int)
+	for i := 0; i < times; i++ {
+		counts[set.Resolve(nil)]++
+	}
+	return counts
+}
+
+func TestReplicaSet_Resolve(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Round robin", func(t *testing.T) {
+		t.Parallel()
+		replicas := newReplicasForTest(t, 1, 1, 1)
+		set, _ := newReplicaSetForTest(t, ReplicaPolicyRoundRobin, replicas)
+		for i := 0; i < 6; i++ {
+			require.Same(t, replicas[i%3].db, set.Resolve(nil))
+		}
+	})
+
+	t.Run("Weighted", func(t *testing.T) {
+		t.Parallel()
+		replicas := newReplicasForTest(t, 1, 3)
+		set, _ := newReplicaSetForTest(t, ReplicaPolicyWeighted, replicas)
+		counts := countResolved(set, 4000)
+		require.InDelta(t, 1000, counts[replicas[0].db], 200)
+		require.InDelta(t, 3000, counts[replicas[1].db], 200)
+	})
+
+	t.Run("Least connections", func(t *testing.T) {
+		t.Parallel()
+		replicas := newReplicasForTest(t, 1, 1)
+		set, _ := newReplicaSetForTest(t, ReplicaPolicyLeastConnections, replicas)
+		conn, err := replicas[0].db.Conn(context.Background())
+		require.NoError(t, err)
+		defer conn.Close()
+		require.Equal(t, map[gorm.ConnPool]int{replicas[1].db: 10}, countResolved(set, 10))
+	})
+
+	t.Run("Random", func(t *testing.T) {
+		t.Parallel()
+		replicas := newReplicasForTest(t, 1, 1)
+		set, _ := newReplicaSetForTest(t, ReplicaPolicyRandom, replicas)
+		counts := countResolved(set, 100)
+		require.Len(t, counts, 2)
+	})
+}
+
+func TestReplicaSet_Check(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	replicas := newReplicasForTest(t, 1, 1)
+	set, master := newReplicaSetForTest(t, ReplicaPolicyRoundRobin, replicas)
+	set.maxLag = time.Second
+	driver := &replicaDriverForTest{lag: map[*sql.DB]time.Duration{replicas[1].db: time.Minute}}
+	set.driver = driver
+	metric := set.metric.(*dbMetricForTest)
+
+	// The lagging replica is removed from rotation
+	set.check(ctx)
+	r.Equal(map[gorm.ConnPool]int{replicas[0].db: 4}, countResolved(set, 4))
+	r.Equal(map[string]bool{"b": false}, metric.active)
+
+	// Reads go to the master while no replica is healthy
+	r.NoError(replicas[0].db.Close())
+	set.check(ctx)
+	r.Equal(map[gorm.ConnPool]int{master: 4}, countResolved(set, 4))
+	r.Equal(map[string]bool{"a": false, "b": false}, metric.active)
+
+	// The replica is added back once it caught up
+	driver.mu.Lock()
+	driver.lag[replicas[1].db] = 0
+	driver.mu.Unlock()
+	set.check(ctx)
+	r.Equal(map[gorm.ConnPool]int{replicas[1].db: 4}, countResolved(set, 4))
+	r.Equal(map[string]bool{"a": false, "b": true}, metric.active)
+
+	// The metrics of closed replicas are removed
+	r.NoError(set.close())
+	r.Empty(metric.active)
+}
+
+func TestNewReplicaSet_Invalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		policy  string
+		weights []int
+	}{
+		{
+			name:    "Unsupported policy",
+			policy:  "fastest",
+			weights: []int{1},
+		},
+		{
+			name:    "Weight not positive",
+			policy:  ReplicaPolicyWeighted,
+			weights: []int{1, 0},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := newReplicaSet("main", &Config{ReplicaPolicy: tc.policy}, nil, newReplicasForTest(t, tc.weights...), nil)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestSetReplicas(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	dir := t.TempDir()
+	masterDB, err := gorm.Open(sqlite.Open(filepath.Join(dir, "master.db")), &gorm.Config{})
+	r.NoError(err)
+	r.NoError(masterDB.Exec("CREATE TABLE pets (id INTEGER PRIMARY KEY, name TEXT)").Error)
+	replicaFile := filepath.Join(dir, "replica.db")
+	replicaDB, err := gorm.Open(sqlite.Open(replicaFile), &gorm.Config{})
+	r.NoError(err)
+	r.NoError(replicaDB.Exec("CREATE TABLE pets (id INTEGER PRIMARY KEY, name TEXT)").Error)
+	r.NoError(replicaDB.Exec("INSERT INTO pets (name) VALUES ('Luna')").Error)
+
+	driver := &replicaDriverForTest{}
+	err = setReplicas(
+		driver,
+		"main",
+		&Config{
+			ReplicaPolicy:        ReplicaPolicyRoundRobin,
+			ReplicaCheckInterval: 10 * time.Millisecond,
+			ReplicaPingTimeout:   time.Second,
+		},
+		masterDB,
+		[]string{"replica"},
+		configs{"replica": Config{Name: replicaFile, ReplicaWeight: 1}},
+	)
+	r.NoError(err)
+
+	// Reads go to the replica, writes to the master
+	var names []string
+	r.NoError(masterDB.Table("pets").Pluck("name", &names).Error)
+	r.Equal([]string{"Luna"}, names)
+	r.NoError(masterDB.Exec("INSERT INTO pets (name) VALUES ('Milo')").Error)
+	r.NoError(masterDB.Table("pets").Pluck("name", &names).Error)
+	r.Equal([]string{"Luna"}, names)
+
+	// The replicas of a master are only set once
+	err = setReplicas(driver, "main", &Config{ReplicaPolicy: ReplicaPolicyRandom}, masterDB, []string{"replica"},
+		configs{"replica": Config{Name: replicaFile}})
+	r.ErrorContains(err, "already set")
+	r.Len(driver.opened, 1)
+
+	// Closing the master stops the health checks and closes the replicas
+	masterSQLDB, err := masterDB.DB()
+	r.NoError(err)
+	set, ok := replicaSets.Load(masterSQLDB)
+	r.True(ok)
+	CloseDB(masterDB)
+	<-set.(*replicaSet).done
+	r.Len(driver.opened, 1)
+	r.ErrorContains(driver.opened[0].Ping(), "database is closed")
+	_, ok = replicaSets.Load(masterSQLDB)
+	r.False(ok)
+}
+
+func TestSetReplicas_Error(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	dir := t.TempDir()
+	masterDB, err := gorm.Open(sqlite.Open(filepath.Join(dir, "master.db")), &gorm.Config{})
+	r.NoError(err)
+
+	// The weight of b is not valid, the replica set can not be created after both replicas were opened
+	driver := &replicaDriverForTest{}
+	err = setReplicas(
+		driver,
+		"main",
+		&Config{ReplicaPolicy: ReplicaPolicyWeighted},
+		masterDB,
+		[]string{"a", "b"},
+		configs{
+			"a": Config{Name: filepath.Join(dir, "a.db"), ReplicaWeight: 1},
+			"b": Config{Name: filepath.Join(dir, "b.db"), ReplicaWeight: -1},
+		},
+	)
+	r.Error(err)
+	r.Len(driver.opened, 2)
+	for _, db := range driver.opened {
+		r.ErrorContains(db.Ping(), "database is closed")
+	}
+}
+
+func TestConfigs_PartialConfig(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	dir := t.TempDir()
+	masterDB, err := gorm.Open(sqlite.Open(filepath.Join(dir, "master.db")), &gorm.Config{})
+	r.NoError(err)
+	t.Cleanup(func() {
+		CloseDB(masterDB)
+	})
+
+	// The fields which are not given get their defaults, like the replica policy
+	c := newOptions(
+		WithConfig("main", Config{ReplicaCheckInterval: time.Hour}),
+		WithConfig("replica", Config{Name: filepath.Join(dir, "replica.db")}),
+	).configs
+	cfg, err := c.get("main")
+	r.NoError(err)
+	r.Equal(ReplicaPolicyRandom, cfg.ReplicaPolicy)
+	r.Equal("warn", cfg.LogLevel)
+	r.Equal(2, cfg.MaxIdleConns)
+	r.Equal(time.Hour, cfg.ReplicaCheckInterval)
+	r.NoError(setReplicas(&replicaDriverForTest{}, "main", cfg, masterDB, []string{"replica"}, c))
+}
@@ -0,0 +1,118 @@
+package env
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultTag = "default"
+
+// SetDefaults sets the zero fields of the struct pointed to by spec to their default tag, like
+// envconfig.Process without reading the environment. Embedded structs are set as well.
+func SetDefaults(spec any) error {
+	v := reflect.ValueOf(spec)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("[Env] %T is not a pointer to a struct", spec)
+	}
+	return setStructDefaults(v.Elem())
+}
+
+func setStructDefaults(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		structField := t.Field(i)
+		if structField.Anonymous && field.Kind() == reflect.Struct {
+			if err := setStructDefaults(field); err != nil {
+				return err
+			}
+			continue
+		}
+		if !structField.IsExported() {
+			continue
+		}
+		value := structField.Tag.Get(defaultTag)
+		if value == "" || !field.IsZero() {
+			continue
+		}
+		if err := setValue(field, value); err != nil {
+			return fmt.Errorf("[Env] invalid default of %s.%s: %w", t.Name(), structField.Name, err)
+		}
+	}
+	return nil
+}
+
+func setValue(field reflect.Value, value string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		// Values are separated by commas, like in envconfig
+		items := strings.Split(value, ",")
+		slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+	case reflect.Map:
+		// Pairs are separated by commas and keys by colons, like in envconfig
+		m := reflect.MakeMap(field.Type())
+		for _, pair := range strings.Split(value, ",") {
+			k, v, ok := strings.Cut(pair, ":")
+			if !ok {
+				return fmt.Errorf("pair %q has no key", pair)
+			}
+			key := reflect.New(field.Type().Key()).Elem()
+			if err := setValue(key, k); err != nil {
+				return err
+			}
+			elem := reflect.New(field.Type().Elem()).Elem()
+			if err := setValue(elem, v); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, elem)
+		}
+		field.Set(m)
+	default:
+		return fmt.Errorf("type %s is not supported", field.Type())
+	}
+	return nil
+}
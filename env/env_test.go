@@ -2,8 +2,10 @@ package env_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/saigontechnology/go-shared-packages/env"
 )
@@ -77,3 +79,37 @@ func TestIsTestEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestSetDefaults(t *testing.T) {
+	type embedded struct {
+		Params map[string]string `default:"a:1,b:2"`
+	}
+	type spec struct {
+		Name    string        `default:"name"`
+		Enabled bool          `default:"true"`
+		Count   int8          `default:"3"`
+		Timeout time.Duration `default:"2s"`
+		Hosts   []string      `default:"a,b"`
+		Empty   []string      `default:""`
+		Given   string        `default:"default"`
+		NoTag   int
+		embedded
+	}
+
+	s := spec{Given: "given"}
+	require.NoError(t, env.SetDefaults(&s))
+	assert.Equal(t, spec{
+		Name:     "name",
+		Enabled:  true,
+		Count:    3,
+		Timeout:  2 * time.Second,
+		Hosts:    []string{"a", "b"},
+		Given:    "given",
+		embedded: embedded{Params: map[string]string{"a": "1", "b": "2"}},
+	}, s)
+
+	assert.Error(t, env.SetDefaults(s))
+	assert.Error(t, env.SetDefaults(&struct {
+		Count int `default:"many"`
+	}{}))
+}
@@ -0,0 +1,27 @@
+package idempotency
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type Config struct {
+	// TTL is how long a response is replayed for its idempotency key
+	TTL time.Duration `default:"24h" envconfig:"IDEMPOTENCY_TTL"`
+	// LockTTL is the lease of the lock held while a request is processed, it is extended until the request ends
+	LockTTL time.Duration `default:"30s" envconfig:"IDEMPOTENCY_LOCK_TTL"`
+	// MaxBodyBytes limits the body read to hash a request, larger bodies get 413. 0 means unlimited
+	MaxBodyBytes int64 `default:"1048576" envconfig:"IDEMPOTENCY_MAX_BODY_BYTES"`
+}
+
+// NewConfig reads the default idempotency configuration from environment variables.
+func NewConfig() (*Config, error) {
+	cfg := &Config{}
+	err := envconfig.Process("", cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
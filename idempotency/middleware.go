@@ -0,0 +1,153 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	authmiddleware "github.com/saigontechnology/go-shared-packages/auth-middleware"
+	"github.com/saigontechnology/go-shared-packages/cache"
+	"github.com/saigontechnology/go-shared-packages/logger"
+)
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed is set on responses replayed from a previous request
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+type storedResponse struct {
+	BodyHash string      `json:"body_hash"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+}
+
+// responseRecorder keeps a copy of the body written to the client.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// GinIdempotencyMiddleware replays the stored response of a request with the same Idempotency-Key header,
+// requests without the header are not affected. Keys are scoped by name and by account when
+// authmiddleware.AuthInjectionMiddleware runs before this middleware.
+// A key reused with a different body gets 422 Unprocessable Entity and a key whose request is still being
+// processed gets 409 Conflict. Server errors are not stored, so the client can retry them.
+// A body larger than Config.MaxBodyBytes gets 413 Request Entity Too Large.
+// Requests are processed normally when the cache fails.
+func GinIdempotencyMiddleware(name string, c cache.Cache, locker cache.Locker, cfg *Config) gin.HandlerFunc {
+	responses := cache.NewTyped[storedResponse](c, cache.JSONCodec{})
+	return func(ctx *gin.Context) {
+		idempotencyKey := ctx.GetHeader(HeaderIdempotencyKey)
+		if idempotencyKey == "" {
+			ctx.Next()
+			return
+		}
+
+		requestBody := ctx.Request.Body
+		if cfg.MaxBodyBytes > 0 {
+			requestBody = http.MaxBytesReader(ctx.Writer, requestBody, cfg.MaxBodyBytes)
+		}
+		body, err := io.ReadAll(requestBody)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+		hash := sha256.Sum256(body)
+		bodyHash := hex.EncodeToString(hash[:])
+		key := fmt.Sprintf("idempotency:%s:%s:%s", name, ctx.GetString(authmiddleware.AccountIDKey), idempotencyKey)
+
+		if replay(ctx, responses, key, bodyHash) {
+			return
+		}
+
+		lock, err := locker.TryLock(ctx, key, cfg.LockTTL)
+		if errors.Is(err, cache.ErrLockNotAcquired) {
+			ctx.AbortWithStatus(http.StatusConflict)
+			return
+		}
+		if err != nil {
+			logger.GetProvider().Logger().Error(ctx, fmt.Sprintf("[Idempotency] could not lock %s: %s", key, err))
+			ctx.Next()
+			return
+		}
+		defer func() {
+			if err := lock.Unlock(context.WithoutCancel(ctx)); err != nil {
+				logger.GetProvider().Logger().Error(ctx, fmt.Sprintf("[Idempotency] could not unlock %s: %s", key, err))
+			}
+		}()
+
+		// The request may have completed while the lock was being acquired
+		if replay(ctx, responses, key, bodyHash) {
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+		ctx.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		response := storedResponse{
+			BodyHash: bodyHash,
+			Status:   status,
+			Header:   recorder.Header().Clone(),
+			Body:     recorder.body.Bytes(),
+		}
+		if err := responses.Set(context.WithoutCancel(ctx), key, response, cfg.TTL, nil); err != nil {
+			logger.GetProvider().Logger().Error(ctx, fmt.Sprintf("[Idempotency] could not store %s: %s", key, err))
+		}
+	}
+}
+
+// replay writes the stored response of key, it reports whether the request was answered.
+func replay(ctx *gin.Context, responses *cache.Typed[storedResponse], key, bodyHash string) bool {
+	response, err := responses.Get(ctx, key, nil)
+	if err != nil {
+		if !errors.Is(err, cache.ErrCacheMiss) {
+			logger.GetProvider().Logger().Error(ctx, fmt.Sprintf("[Idempotency] could not get %s: %s", key, err))
+		}
+		return false
+	}
+
+	if response.BodyHash != bodyHash {
+		ctx.AbortWithStatus(http.StatusUnprocessableEntity)
+		return true
+	}
+	// Headers set again by the middlewares before this one are replaced, not duplicated
+	for name, values := range response.Header {
+		ctx.Writer.Header()[name] = values
+	}
+	ctx.Header(HeaderIdempotentReplayed, "true")
+	ctx.Writer.WriteHeader(response.Status)
+	//nolint: errcheck
+	ctx.Writer.Write(response.Body)
+	ctx.Abort()
+	return true
+}
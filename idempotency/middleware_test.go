@@ -0,0 +1,119 @@
+package idempotency_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/saigontechnology/go-shared-packages/cache"
+	"github.com/saigontechnology/go-shared-packages/idempotency"
+)
+
+func newRequestForTest(key, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(idempotency.HeaderIdempotencyKey, key)
+	}
+	return req
+}
+
+func TestGinIdempotencyMiddleware(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	c := cache.NewMiniRedisForTest(t)
+	locker, err := cache.NewLocker(c)
+	r.NoError(err)
+	cfg := &idempotency.Config{TTL: time.Hour, LockTTL: time.Minute}
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	engine := gin.New()
+	engine.POST("/orders", idempotency.GinIdempotencyMiddleware("orders", c, locker, cfg), func(c *gin.Context) {
+		n := calls.Add(1)
+		if c.Query("wait") != "" {
+			<-release
+		}
+		c.Header("X-Order", "created")
+		c.String(http.StatusCreated, "order %d", n)
+	})
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, newRequestForTest("key-1", `{"amount":1}`))
+	r.Equal(http.StatusCreated, rec.Code)
+	r.Equal("order 1", rec.Body.String())
+
+	// A retry gets the stored response
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, newRequestForTest("key-1", `{"amount":1}`))
+	r.Equal(http.StatusCreated, rec.Code)
+	r.Equal("order 1", rec.Body.String())
+	r.Equal("created", rec.Header().Get("X-Order"))
+	r.Equal("true", rec.Header().Get(idempotency.HeaderIdempotentReplayed))
+	r.Equal(int32(1), calls.Load())
+
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, newRequestForTest("key-1", `{"amount":2}`))
+	r.Equal(http.StatusUnprocessableEntity, rec.Code)
+
+	// Requests without a key are not affected
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, newRequestForTest("", `{"amount":1}`))
+	r.Equal("order 2", rec.Body.String())
+
+	// A request reusing the key of a request in progress is rejected
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := newRequestForTest("key-2", `{}`)
+		req.URL.RawQuery = "wait=true"
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	r.Eventually(func() bool {
+		return calls.Load() == 3
+	}, time.Second, 10*time.Millisecond)
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, newRequestForTest("key-2", `{}`))
+	r.Equal(http.StatusConflict, rec.Code)
+	close(release)
+	<-done
+
+	var stored []byte
+	r.NoError(c.Get(context.Background(), "idempotency:orders::key-2", &stored, nil))
+	r.NotEmpty(stored)
+}
+
+func TestGinIdempotencyMiddleware_Replay(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	c := cache.NewMiniRedisForTest(t)
+	locker, err := cache.NewLocker(c)
+	r.NoError(err)
+	cfg := &idempotency.Config{TTL: time.Hour, LockTTL: time.Minute, MaxBodyBytes: 16}
+
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Header("Access-Control-Allow-Origin", "*")
+	})
+	engine.POST("/orders", idempotency.GinIdempotencyMiddleware("orders", c, locker, cfg), func(c *gin.Context) {
+		c.String(http.StatusCreated, "created")
+	})
+
+	// Headers of the middlewares before are not duplicated in a replayed response
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, newRequestForTest("key-1", `{}`))
+		r.Equal(http.StatusCreated, rec.Code)
+		r.Equal([]string{"*"}, rec.Header().Values("Access-Control-Allow-Origin"))
+	}
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, newRequestForTest("key-2", `{"note":"too long to be hashed"}`))
+	r.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+}
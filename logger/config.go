@@ -2,6 +2,8 @@ package logger
 
 import (
 	"github.com/kelseyhightower/envconfig"
+
+	"github.com/saigontechnology/go-shared-packages/env"
 )
 
 const (
@@ -9,7 +11,7 @@ const (
 	EnvDev  = "dev"
 )
 
-type loggerConfig struct {
+type Config struct {
 	Env              string `envconfig:"ENV"                 default:"dev"`
 	Enabled          bool   `envconfig:"LOG_ENABLED"         default:"true"`
 	Level            int8   `envconfig:"LOG_LEVEL"           default:"0"`
@@ -19,16 +21,16 @@ type loggerConfig struct {
 	EnableTracing    bool   `envconfig:"LOG_ENABLE_TRACING"  default:"false"`
 }
 
-func (c *loggerConfig) IsTestEnv() bool {
+func (c *Config) IsTestEnv() bool {
 	return c.Env == EnvTest
 }
 
-func (c *loggerConfig) IsDevEnv() bool {
+func (c *Config) IsDevEnv() bool {
 	return c.Env == EnvDev
 }
 
-func newLoggerConfig() (*loggerConfig, error) {
-	cfg := &loggerConfig{}
+func NewConfig() (*Config, error) {
+	cfg := &Config{}
 	err := envconfig.Process("", cfg)
 	if err != nil {
 		return nil, err
@@ -37,6 +39,66 @@ func newLoggerConfig() (*loggerConfig, error) {
 	return cfg, nil
 }
 
+// Option changes the configuration of a Logger created by New.
+type Option func(o *options)
+
+type options struct {
+	// cfg is given by WithConfig, the environment is not read when it is set
+	cfg     *Config
+	changes []func(cfg *Config)
+}
+
+// newConfigFromOptions applies the changes of the other options on top of the configuration
+// given by WithConfig and completed with defaults, or read from the environment without WithConfig.
+func newConfigFromOptions(opts ...Option) (*Config, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	cfg := o.cfg
+	if cfg == nil {
+		var err error
+		cfg, err = NewConfig()
+		if err != nil {
+			return nil, err
+		}
+	} else if err := env.SetDefaults(cfg); err != nil {
+		return nil, err
+	}
+	for _, change := range o.changes {
+		change(cfg)
+	}
+	return cfg, nil
+}
+
+func withChange(change func(cfg *Config)) Option {
+	return func(o *options) {
+		o.changes = append(o.changes, change)
+	}
+}
+
+// WithConfig replaces the configuration read from the environment, which is not read at all.
+// Fields left at their zero value get the default of their environment variable.
+func WithConfig(c Config) Option {
+	return func(o *options) {
+		o.cfg = &c
+	}
+}
+
+// WithLevel sets the minimum level of logged messages, see zapcore.Level.
+func WithLevel(level int8) Option {
+	return withChange(func(cfg *Config) {
+		cfg.Level = level
+	})
+}
+
+// WithAppRole sets the app_role field of all messages.
+func WithAppRole(appRole string) Option {
+	return withChange(func(cfg *Config) {
+		cfg.AppRole = appRole
+	})
+}
+
 type RequestLogConfig struct {
 	Enabled         bool     `envconfig:"REQUEST_LOG_ENABLED"       default:"false"`
 	LoggingResponse bool     `envconfig:"REQUEST_LOG_WITH_RESPONSE" default:"false"`
@@ -20,19 +20,10 @@ type provider struct {
 // GetProvider singleton implementation makes sure only one Provider is created to avoid duplicated logger
 func GetProvider() Provider {
 	providerOnce.Do(func() {
-		cfg, err := newLoggerConfig()
+		l, err := New()
 		if err != nil {
 			panic(err)
 		}
-		var l Logger
-		if !cfg.Enabled || cfg.IsTestEnv() {
-			l = NewNoopLogger()
-		} else {
-			l, err = NewZapLogger(cfg)
-			if err != nil {
-				panic(err)
-			}
-		}
 
 		providerInstance = &provider{
 			l: l,
@@ -42,6 +33,19 @@ func GetProvider() Provider {
 	return providerInstance
 }
 
+// New creates a Logger, options are applied in order to the configuration given by WithConfig
+// or read from the environment. A NoopLogger is returned when logging is disabled or in the test environment.
+func New(options ...Option) (Logger, error) {
+	cfg, err := newConfigFromOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Enabled || cfg.IsTestEnv() {
+		return NewNoopLogger(), nil
+	}
+	return NewZapLogger(cfg)
+}
+
 func (p *provider) Logger() Logger {
 	return p.l
 }
@@ -0,0 +1,23 @@
+package logger_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/saigontechnology/go-shared-packages/logger"
+)
+
+func TestNew_PartialConfig(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	// Enabled and Env are not given, they get their defaults instead of disabling the logger
+	l, err := logger.New(logger.WithConfig(logger.Config{AppRole: "worker"}))
+	r.NoError(err)
+	r.IsType(&logger.ZapLogger{}, l)
+
+	l, err = logger.New(logger.WithConfig(logger.Config{Env: logger.EnvTest}))
+	r.NoError(err)
+	r.IsType(&logger.NoopLogger{}, l)
+}
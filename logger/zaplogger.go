@@ -16,11 +16,11 @@ const (
 )
 
 type ZapLogger struct {
-	cfg *loggerConfig
+	cfg *Config
 	zl  *zap.Logger
 }
 
-func NewZapLogger(cfg *loggerConfig) (Logger, error) {
+func NewZapLogger(cfg *Config) (Logger, error) {
 	encoderConfig := zap.NewProductionEncoderConfig()
 	encoderConfig.MessageKey = logFieldMessage
 	encoderConfig.TimeKey = logFieldTimestamp
@@ -1,6 +1,10 @@
 package migration
 
-import "github.com/kelseyhightower/envconfig"
+import (
+	"github.com/kelseyhightower/envconfig"
+
+	"github.com/saigontechnology/go-shared-packages/database"
+)
 
 type config struct {
 	Host      string `default:"localhost"                       envconfig:"MIGRATION_DB_HOST"`
@@ -10,6 +14,8 @@ type config struct {
 	Password  string `default:"pet-db"                          envconfig:"MIGRATION_DB_PASSWORD"`
 	Charset   string `default:"utf8mb4"                         envconfig:"MIGRATION_DB_CHARSET"`
 	SourceURL string `default:"file://./internal/db/migrations" envconfig:"MIGRATION_SOURCE_URL"`
+	// Postgres are read from MIGRATION_DB_SSL_MODE and so on, DB_SSL_MODE is used when they are not set
+	Postgres database.PostgresParams `ignored:"true"`
 }
 
 func newConfig() (*config, error) {
@@ -18,6 +24,10 @@ func newConfig() (*config, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = envconfig.Process("MIGRATION", &cfg.Postgres)
+	if err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
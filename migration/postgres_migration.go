@@ -10,6 +10,7 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
 
+	"github.com/saigontechnology/go-shared-packages/database"
 	"github.com/saigontechnology/go-shared-packages/must"
 
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -26,14 +27,7 @@ type postgresMigration struct {
 func NewPostgresMigration(tablePrefix string) PostgresMigration {
 	cfg, err := newConfig()
 	must.NotFail(err)
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Ho_Chi_Minh",
-		cfg.Host,
-		cfg.Username,
-		cfg.Password,
-		cfg.Name,
-		cfg.Port,
-	)
+	dsn := database.PostgresDSN(cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.Name, cfg.Postgres)
 	gormDB, err := gorm.Open(gormpostgres.Open(dsn), &gorm.Config{
 		NamingStrategy: schema.NamingStrategy{
 			TablePrefix: tablePrefix,
@@ -15,15 +15,24 @@ const (
 	vectorCacheMiss = "miss"
 	vectorCacheGet  = "get"
 	vectorCacheSet  = "set"
+	vectorCacheLoad = "load"
+
+	vectorCacheTierLocal  = "local"
+	vectorCacheTierRemote = "remote"
 
 	vectorTag    = "tag"
 	vectorStatus = "status"
 	vectorOp     = "op"
+	vectorTier   = "tier"
 
 	nameCacheHitTotal        = "cache_hit_total"
 	descriptionCacheHit      = "Monitor cache hit by tag"
 	nameCacheDurationSeconds = "cache_duration_seconds"
 	descriptionCacheDuration = "Monitor cache latency by tag"
+	nameCacheTierHitTotal    = "cache_tier_hit_total"
+	descriptionCacheTierHit  = "Monitor cache hit of a two-tier cache by tag and tier"
+	nameCacheBreakerState    = "cache_breaker_state"
+	descriptionCacheBreaker  = "Monitor cache circuit breaker state, 0 is closed, 1 is half-open and 2 is open"
 )
 
 var (
@@ -34,14 +43,20 @@ var (
 type CacheMetric interface {
 	CountCacheHit(tag *string)
 	CountCacheMiss(tag *string)
+	CountCacheLocalHit(tag *string)
+	CountCacheRemoteHit(tag *string)
 	NewCacheGetLatencyTransaction(tag *string) CacheLatencyMetricTxn
 	NewCacheSetLatencyTransaction(tag *string) CacheLatencyMetricTxn
+	NewCacheLoadLatencyTransaction(tag *string) CacheLatencyMetricTxn
+	SetCacheBreakerState(state int)
 }
 
 type cacheMetric struct {
-	cfg           *cacheMetricConfig
-	cacheLatency  *prometheus.HistogramVec
-	cacheHitTotal *prometheus.CounterVec
+	cfg               *cacheMetricConfig
+	cacheLatency      *prometheus.HistogramVec
+	cacheHitTotal     *prometheus.CounterVec
+	cacheTierHitTotal *prometheus.CounterVec
+	cacheBreakerState prometheus.Gauge
 }
 
 func GetCacheMetric() CacheMetric {
@@ -65,10 +80,24 @@ func GetCacheMetric() CacheMetric {
 			),
 		}, []string{vectorTag, vectorOp})
 		prometheus.MustRegister(cacheLatency)
+		cacheTierHitTotal := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Metric.Namespace,
+			Name:      fmt.Sprintf("%s_%s", cfg.Metric.MetricPrefix, nameCacheTierHitTotal),
+			Help:      descriptionCacheTierHit,
+		}, []string{vectorTag, vectorTier})
+		prometheus.MustRegister(cacheTierHitTotal)
+		cacheBreakerState := prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cfg.Metric.Namespace,
+			Name:      fmt.Sprintf("%s_%s", cfg.Metric.MetricPrefix, nameCacheBreakerState),
+			Help:      descriptionCacheBreaker,
+		})
+		prometheus.MustRegister(cacheBreakerState)
 		cacheMetricInstance = &cacheMetric{
-			cfg:           cfg,
-			cacheHitTotal: cacheHitTotal,
-			cacheLatency:  cacheLatency,
+			cfg:               cfg,
+			cacheHitTotal:     cacheHitTotal,
+			cacheLatency:      cacheLatency,
+			cacheTierHitTotal: cacheTierHitTotal,
+			cacheBreakerState: cacheBreakerState,
 		}
 	})
 
@@ -83,6 +112,34 @@ func (m *cacheMetric) CountCacheMiss(tag *string) {
 	m.countCacheOp(tag, vectorCacheMiss)
 }
 
+// CountCacheLocalHit counts a hit served by the in-process tier of a two-tier cache.
+func (m *cacheMetric) CountCacheLocalHit(tag *string) {
+	m.countCacheTierHit(tag, vectorCacheTierLocal)
+}
+
+// CountCacheRemoteHit counts a hit served by the Redis tier of a two-tier cache.
+func (m *cacheMetric) CountCacheRemoteHit(tag *string) {
+	m.countCacheTierHit(tag, vectorCacheTierRemote)
+}
+
+func (m *cacheMetric) countCacheTierHit(tag *string, tier string) {
+	if !m.cfg.CacheMetricEnabled {
+		return
+	}
+	if tag == nil {
+		return
+	}
+	m.cacheTierHitTotal.WithLabelValues(*tag, tier).Inc()
+}
+
+// SetCacheBreakerState reports the state of the cache circuit breaker.
+func (m *cacheMetric) SetCacheBreakerState(state int) {
+	if !m.cfg.CacheMetricEnabled {
+		return
+	}
+	m.cacheBreakerState.Set(float64(state))
+}
+
 func (m *cacheMetric) countCacheOp(tag *string, status string) {
 	if !m.cfg.CacheMetricEnabled {
 		return
@@ -119,6 +176,20 @@ func (m *cacheMetric) NewCacheSetLatencyTransaction(tag *string) CacheLatencyMet
 	}
 }
 
+// NewCacheLoadLatencyTransaction measures the latency of loading a value from the source of truth on a cache miss.
+func (m *cacheMetric) NewCacheLoadLatencyTransaction(tag *string) CacheLatencyMetricTxn {
+	if !m.cfg.CacheMetricEnabled || tag == nil {
+		return &nullCacheLatencyTxn{}
+	}
+
+	return &cacheLatencyMetricTxn{
+		op:            vectorCacheLoad,
+		tag:           *tag,
+		start:         time.Now(),
+		latencyMetric: m.cacheLatency,
+	}
+}
+
 type CacheLatencyMetricTxn interface {
 	End()
 }
@@ -0,0 +1,137 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/saigontechnology/go-shared-packages/must"
+)
+
+const (
+	vectorNamespace = "namespace"
+	vectorState     = "state"
+
+	vectorConnTotal = "total"
+	vectorConnIdle  = "idle"
+	vectorConnStale = "stale"
+
+	nameCacheUp                 = "cache_up"
+	descriptionCacheUp          = "Monitor whether Redis answers a ping, 1 is up and 0 is down"
+	nameCachePingSeconds        = "cache_ping_duration_seconds"
+	descriptionCachePing        = "Monitor the latency of a Redis ping"
+	nameCachePoolHitTotal       = "cache_pool_hit_total"
+	descriptionCachePoolHit     = "Monitor the number of times a free connection was found in the Redis pool"
+	nameCachePoolMissTotal      = "cache_pool_miss_total"
+	descriptionCachePoolMiss    = "Monitor the number of times a free connection was not found in the Redis pool"
+	nameCachePoolTimeoutTotal   = "cache_pool_timeout_total"
+	descriptionCachePoolTimeout = "Monitor the number of times waiting for a connection of the Redis pool timed out"
+	nameCachePoolConnections    = "cache_pool_connections"
+	descriptionCachePoolConns   = "Monitor the connections of the Redis pool by state, stale connections are the ones removed"
+	nameCacheKeys               = "cache_keys"
+	descriptionCacheKeys        = "Monitor the number of Redis keys by namespace"
+)
+
+// cacheStatsCollectTimeout bounds how long a scrape waits for the statistics
+const cacheStatsCollectTimeout = 5 * time.Second
+
+var cacheStatsCollectorOnce sync.Once
+
+// CacheStats is a snapshot of the health, connection pool and keyspace of Redis.
+type CacheStats struct {
+	Up          bool
+	PingLatency time.Duration
+	Hits        uint32
+	Misses      uint32
+	Timeouts    uint32
+	TotalConns  uint32
+	IdleConns   uint32
+	StaleConns  uint32
+	// Keys is the number of keys by namespace
+	Keys map[string]int64
+}
+
+// CacheStatsReader is read each time metrics are scraped, it is implemented by the cache package.
+type CacheStatsReader interface {
+	ReadCacheStats(ctx context.Context) CacheStats
+}
+
+type cacheStatsCollector struct {
+	reader      CacheStatsReader
+	up          *prometheus.Desc
+	pingLatency *prometheus.Desc
+	poolHits    *prometheus.Desc
+	poolMisses  *prometheus.Desc
+	poolTimeout *prometheus.Desc
+	poolConns   *prometheus.Desc
+	keys        *prometheus.Desc
+}
+
+// RegisterCacheStatsCollector registers the Redis statistics of reader next to the other cache metrics
+// when PROMETHEUS_CACHE_METRIC_ENABLED is true. Only the first reader is registered.
+func RegisterCacheStatsCollector(reader CacheStatsReader) {
+	cacheStatsCollectorOnce.Do(func() {
+		cfg, err := newCacheMetricConfig()
+		must.NotFail(err)
+		if !cfg.CacheMetricEnabled {
+			return
+		}
+		prometheus.MustRegister(newCacheStatsCollector(cfg.Metric, reader))
+	})
+}
+
+func newCacheStatsCollector(cfg *metricConfig, reader CacheStatsReader) *cacheStatsCollector {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(cfg.Namespace, "", fmt.Sprintf("%s_%s", cfg.MetricPrefix, name)),
+			help,
+			labels,
+			nil,
+		)
+	}
+	return &cacheStatsCollector{
+		reader:      reader,
+		up:          desc(nameCacheUp, descriptionCacheUp),
+		pingLatency: desc(nameCachePingSeconds, descriptionCachePing),
+		poolHits:    desc(nameCachePoolHitTotal, descriptionCachePoolHit),
+		poolMisses:  desc(nameCachePoolMissTotal, descriptionCachePoolMiss),
+		poolTimeout: desc(nameCachePoolTimeoutTotal, descriptionCachePoolTimeout),
+		poolConns:   desc(nameCachePoolConnections, descriptionCachePoolConns, vectorState),
+		keys:        desc(nameCacheKeys, descriptionCacheKeys, vectorNamespace),
+	}
+}
+
+func (c *cacheStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.up
+	ch <- c.pingLatency
+	ch <- c.poolHits
+	ch <- c.poolMisses
+	ch <- c.poolTimeout
+	ch <- c.poolConns
+	ch <- c.keys
+}
+
+func (c *cacheStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), cacheStatsCollectTimeout)
+	defer cancel()
+	stats := c.reader.ReadCacheStats(ctx)
+
+	up := 0.0
+	if stats.Up {
+		up = 1
+		ch <- prometheus.MustNewConstMetric(c.pingLatency, prometheus.GaugeValue, stats.PingLatency.Seconds())
+	}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, up)
+	ch <- prometheus.MustNewConstMetric(c.poolHits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.poolMisses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.poolTimeout, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.poolConns, prometheus.GaugeValue, float64(stats.TotalConns), vectorConnTotal)
+	ch <- prometheus.MustNewConstMetric(c.poolConns, prometheus.GaugeValue, float64(stats.IdleConns), vectorConnIdle)
+	ch <- prometheus.MustNewConstMetric(c.poolConns, prometheus.GaugeValue, float64(stats.StaleConns), vectorConnStale)
+	for namespace, keys := range stats.Keys {
+		ch <- prometheus.MustNewConstMetric(c.keys, prometheus.GaugeValue, float64(keys), namespace)
+	}
+}
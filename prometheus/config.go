@@ -31,6 +31,19 @@ type insideMetricConfig struct {
 	InsideLatencyBucketCount int     `default:"3"    envconfig:"PROMETHEUS_INSIDE_LATENCY_BUCKET_COUNT"`
 }
 
+type dbMetricConfig struct {
+	Metric               *metricConfig
+	DBMetricEnabled      bool    `default:"true" envconfig:"PROMETHEUS_DB_METRIC_ENABLED"`
+	DBLatencyBucketStart float64 `default:"0.01" envconfig:"PROMETHEUS_DB_LATENCY_BUCKET_START"`
+	DBLatencyBucketWidth float64 `default:"0.05" envconfig:"PROMETHEUS_DB_LATENCY_BUCKET_WIDTH"`
+	DBLatencyBucketCount int     `default:"5"    envconfig:"PROMETHEUS_DB_LATENCY_BUCKET_COUNT"`
+}
+
+type rateLimitMetricConfig struct {
+	Metric                 *metricConfig
+	RateLimitMetricEnabled bool `default:"true" envconfig:"PROMETHEUS_RATE_LIMIT_METRIC_ENABLED"`
+}
+
 func newHandlerMetricConfig() (*handlerMetricConfig, error) {
 	metricCfg := &metricConfig{}
 	if err := envconfig.Process("", metricCfg); err != nil {
@@ -71,3 +84,31 @@ func newInsideMetricConfig() (*insideMetricConfig, error) {
 	cfg.Metric = metricCfg
 	return cfg, nil
 }
+
+func newRateLimitMetricConfig() (*rateLimitMetricConfig, error) {
+	metricCfg := &metricConfig{}
+	if err := envconfig.Process("", metricCfg); err != nil {
+		return nil, err
+	}
+	cfg := &rateLimitMetricConfig{}
+	err := envconfig.Process("", cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Metric = metricCfg
+	return cfg, nil
+}
+
+func newDBMetricConfig() (*dbMetricConfig, error) {
+	metricCfg := &metricConfig{}
+	if err := envconfig.Process("", metricCfg); err != nil {
+		return nil, err
+	}
+	cfg := &dbMetricConfig{}
+	err := envconfig.Process("", cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Metric = metricCfg
+	return cfg, nil
+}
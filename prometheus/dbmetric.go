@@ -0,0 +1,295 @@
+package prometheus
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/saigontechnology/go-shared-packages/must"
+)
+
+const (
+	vectorDB      = "db"
+	vectorTable   = "table"
+	vectorReplica = "replica"
+
+	nameDBReplicaActive        = "db_replica_active"
+	descriptionDBReplicaActive = "Monitor whether a replica receives reads, 1 is in rotation and 0 is removed"
+	nameDBReplicaLagSeconds    = "db_replica_lag_seconds"
+	descriptionDBReplicaLag    = "Monitor the replication lag of a replica"
+
+	nameDBQueryDurationSeconds = "db_query_duration_seconds"
+	descriptionDBQueryDuration = "Monitor the latency of database queries by table and operation"
+	nameDBQueryErrorTotal      = "db_query_error_total"
+	descriptionDBQueryError    = "Monitor the database queries which failed by table and operation"
+	nameDBQueryRowsTotal       = "db_query_rows_total"
+	descriptionDBQueryRows     = "Monitor the rows affected or returned by database queries by table and operation"
+
+	nameDBMaxOpenConnections        = "db_max_open_connections"
+	descriptionDBMaxOpenConnections = "Monitor the maximum number of open connections of a database pool, 0 is unlimited"
+	nameDBOpenConnections           = "db_open_connections"
+	descriptionDBOpenConnections    = "Monitor the number of open connections of a database pool, in use and idle"
+	nameDBInUseConnections          = "db_in_use_connections"
+	descriptionDBInUseConnections   = "Monitor the number of connections of a database pool in use"
+	nameDBIdleConnections           = "db_idle_connections"
+	descriptionDBIdleConnections    = "Monitor the number of idle connections of a database pool"
+	nameDBWaitTotal                 = "db_wait_total"
+	descriptionDBWait               = "Monitor the number of times a connection of a database pool was waited for"
+	nameDBWaitDurationSecondsTotal  = "db_wait_duration_seconds_total"
+	descriptionDBWaitDuration       = "Monitor the time spent waiting for a connection of a database pool"
+	nameDBClosedMaxIdleTotal        = "db_closed_max_idle_total"
+	descriptionDBClosedMaxIdle      = "Monitor the connections of a database pool closed due to the maximum of idle connections"
+	nameDBClosedMaxIdleTimeTotal    = "db_closed_max_idle_time_total"
+	descriptionDBClosedMaxIdleTime  = "Monitor the connections of a database pool closed due to their idle time"
+	nameDBClosedMaxLifetimeTotal    = "db_closed_max_lifetime_total"
+	descriptionDBClosedMaxLifetime  = "Monitor the connections of a database pool closed due to their lifetime"
+)
+
+var (
+	dbMetricOnce     sync.Once
+	dbMetricInstance *dbMetric
+)
+
+type DBMetric interface {
+	// AddDB reports the pool statistics of db labelled by name.
+	// The statistics of pools added with the same name, like the pools of two connectors, are summed up.
+	AddDB(name string, db *sql.DB)
+	// RemoveDB stops reporting the pool statistics of db, it is called when db is closed.
+	RemoveDB(db *sql.DB)
+	// ObserveQuery records a query run on the database name, err is nil when the query succeeded.
+	ObserveQuery(name, table, operation string, duration time.Duration, rows int64, err error)
+	// SetReplicaActive reports whether the replica of the database name is in rotation.
+	SetReplicaActive(name, replica string, active bool)
+	// SetReplicaLag reports the replication lag of the replica of the database name.
+	SetReplicaLag(name, replica string, lag time.Duration)
+	// RemoveReplica stops reporting whether the replica of the database name is active and its lag,
+	// it is called when the replica is closed.
+	RemoveReplica(name, replica string)
+}
+
+type dbMetric struct {
+	cfg           *dbMetricConfig
+	pool          *dbPoolCollector
+	queryLatency  *prometheus.HistogramVec
+	queryErrors   *prometheus.CounterVec
+	queryRows     *prometheus.CounterVec
+	replicaActive *prometheus.GaugeVec
+	replicaLag    *prometheus.GaugeVec
+}
+
+func GetDBMetric() DBMetric {
+	dbMetricOnce.Do(func() {
+		cfg, err := newDBMetricConfig()
+		must.NotFail(err)
+		pool := newDBPoolCollector(cfg.Metric)
+		prometheus.MustRegister(pool)
+		queryLatency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.Metric.Namespace,
+			Name:      fmt.Sprintf("%s_%s", cfg.Metric.MetricPrefix, nameDBQueryDurationSeconds),
+			Help:      descriptionDBQueryDuration,
+			Buckets: prometheus.LinearBuckets(
+				cfg.DBLatencyBucketStart,
+				cfg.DBLatencyBucketWidth,
+				cfg.DBLatencyBucketCount,
+			),
+		}, []string{vectorDB, vectorTable, vectorOp})
+		prometheus.MustRegister(queryLatency)
+		queryErrors := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Metric.Namespace,
+			Name:      fmt.Sprintf("%s_%s", cfg.Metric.MetricPrefix, nameDBQueryErrorTotal),
+			Help:      descriptionDBQueryError,
+		}, []string{vectorDB, vectorTable, vectorOp})
+		prometheus.MustRegister(queryErrors)
+		queryRows := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Metric.Namespace,
+			Name:      fmt.Sprintf("%s_%s", cfg.Metric.MetricPrefix, nameDBQueryRowsTotal),
+			Help:      descriptionDBQueryRows,
+		}, []string{vectorDB, vectorTable, vectorOp})
+		prometheus.MustRegister(queryRows)
+		replicaActive := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: cfg.Metric.Namespace,
+			Name:      fmt.Sprintf("%s_%s", cfg.Metric.MetricPrefix, nameDBReplicaActive),
+			Help:      descriptionDBReplicaActive,
+		}, []string{vectorDB, vectorReplica})
+		prometheus.MustRegister(replicaActive)
+		replicaLag := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: cfg.Metric.Namespace,
+			Name:      fmt.Sprintf("%s_%s", cfg.Metric.MetricPrefix, nameDBReplicaLagSeconds),
+			Help:      descriptionDBReplicaLag,
+		}, []string{vectorDB, vectorReplica})
+		prometheus.MustRegister(replicaLag)
+		dbMetricInstance = &dbMetric{
+			cfg:           cfg,
+			pool:          pool,
+			queryLatency:  queryLatency,
+			queryErrors:   queryErrors,
+			queryRows:     queryRows,
+			replicaActive: replicaActive,
+			replicaLag:    replicaLag,
+		}
+	})
+
+	return dbMetricInstance
+}
+
+func (m *dbMetric) AddDB(name string, db *sql.DB) {
+	if !m.cfg.DBMetricEnabled {
+		return
+	}
+	m.pool.add(name, db)
+}
+
+func (m *dbMetric) RemoveDB(db *sql.DB) {
+	m.pool.remove(db)
+}
+
+func (m *dbMetric) ObserveQuery(name, table, operation string, duration time.Duration, rows int64, err error) {
+	if !m.cfg.DBMetricEnabled {
+		return
+	}
+	m.queryLatency.WithLabelValues(name, table, operation).Observe(duration.Seconds())
+	if err != nil {
+		m.queryErrors.WithLabelValues(name, table, operation).Inc()
+	}
+	if rows > 0 {
+		m.queryRows.WithLabelValues(name, table, operation).Add(float64(rows))
+	}
+}
+
+func (m *dbMetric) SetReplicaActive(name, replica string, active bool) {
+	if !m.cfg.DBMetricEnabled {
+		return
+	}
+	value := 0.0
+	if active {
+		value = 1
+	}
+	m.replicaActive.WithLabelValues(name, replica).Set(value)
+}
+
+func (m *dbMetric) SetReplicaLag(name, replica string, lag time.Duration) {
+	if !m.cfg.DBMetricEnabled {
+		return
+	}
+	m.replicaLag.WithLabelValues(name, replica).Set(lag.Seconds())
+}
+
+func (m *dbMetric) RemoveReplica(name, replica string) {
+	m.replicaActive.DeleteLabelValues(name, replica)
+	m.replicaLag.DeleteLabelValues(name, replica)
+}
+
+// dbPoolCollector reads sql.DBStats of every database pool each time metrics are scraped.
+type dbPoolCollector struct {
+	mu sync.Mutex
+	// dbs are the names of the pools
+	dbs map[*sql.DB]string
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	wait              *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	closedMaxIdle     *prometheus.Desc
+	closedMaxIdleTime *prometheus.Desc
+	closedMaxLifetime *prometheus.Desc
+}
+
+func newDBPoolCollector(cfg *metricConfig) *dbPoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(cfg.Namespace, "", fmt.Sprintf("%s_%s", cfg.MetricPrefix, name)),
+			help,
+			[]string{vectorDB},
+			nil,
+		)
+	}
+	return &dbPoolCollector{
+		dbs:               make(map[*sql.DB]string),
+		maxOpen:           desc(nameDBMaxOpenConnections, descriptionDBMaxOpenConnections),
+		open:              desc(nameDBOpenConnections, descriptionDBOpenConnections),
+		inUse:             desc(nameDBInUseConnections, descriptionDBInUseConnections),
+		idle:              desc(nameDBIdleConnections, descriptionDBIdleConnections),
+		wait:              desc(nameDBWaitTotal, descriptionDBWait),
+		waitDuration:      desc(nameDBWaitDurationSecondsTotal, descriptionDBWaitDuration),
+		closedMaxIdle:     desc(nameDBClosedMaxIdleTotal, descriptionDBClosedMaxIdle),
+		closedMaxIdleTime: desc(nameDBClosedMaxIdleTimeTotal, descriptionDBClosedMaxIdleTime),
+		closedMaxLifetime: desc(nameDBClosedMaxLifetimeTotal, descriptionDBClosedMaxLifetime),
+	}
+}
+
+func (c *dbPoolCollector) add(name string, db *sql.DB) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dbs[db] = name
+}
+
+func (c *dbPoolCollector) remove(db *sql.DB) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.dbs, db)
+}
+
+func (c *dbPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.wait
+	ch <- c.waitDuration
+	ch <- c.closedMaxIdle
+	ch <- c.closedMaxIdleTime
+	ch <- c.closedMaxLifetime
+}
+
+// Collect sums up the statistics of the pools with the same name.
+func (c *dbPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	byName := make(map[string]sql.DBStats)
+	for db, name := range c.dbs {
+		stats := db.Stats()
+		total, seen := byName[name]
+		if stats.MaxOpenConnections == 0 || (seen && total.MaxOpenConnections == 0) {
+			// One unlimited pool makes the sum unlimited
+			total.MaxOpenConnections = 0
+		} else {
+			total.MaxOpenConnections += stats.MaxOpenConnections
+		}
+		total.OpenConnections += stats.OpenConnections
+		total.InUse += stats.InUse
+		total.Idle += stats.Idle
+		total.WaitCount += stats.WaitCount
+		total.WaitDuration += stats.WaitDuration
+		total.MaxIdleClosed += stats.MaxIdleClosed
+		total.MaxIdleTimeClosed += stats.MaxIdleTimeClosed
+		total.MaxLifetimeClosed += stats.MaxLifetimeClosed
+		byName[name] = total
+	}
+	c.mu.Unlock()
+
+	for name, stats := range byName {
+		ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections), name)
+		ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(stats.OpenConnections), name)
+		ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse), name)
+		ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle), name)
+		ch <- prometheus.MustNewConstMetric(c.wait, prometheus.CounterValue, float64(stats.WaitCount), name)
+		ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds(), name)
+		ch <- prometheus.MustNewConstMetric(c.closedMaxIdle, prometheus.CounterValue, float64(stats.MaxIdleClosed), name)
+		ch <- prometheus.MustNewConstMetric(
+			c.closedMaxIdleTime,
+			prometheus.CounterValue,
+			float64(stats.MaxIdleTimeClosed),
+			name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.closedMaxLifetime,
+			prometheus.CounterValue,
+			float64(stats.MaxLifetimeClosed),
+			name,
+		)
+	}
+}
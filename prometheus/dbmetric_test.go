@@ -0,0 +1,69 @@
+package prometheus
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// connectorForTest never connects, pools report their statistics without connections.
+type connectorForTest struct{}
+
+func (c connectorForTest) Connect(context.Context) (driver.Conn, error) {
+	return nil, errors.New("not connected")
+}
+
+func (c connectorForTest) Driver() driver.Driver {
+	return nil
+}
+
+func newDBForTest(t *testing.T, maxOpen int) *sql.DB {
+	t.Helper()
+
+	db := sql.OpenDB(connectorForTest{})
+	db.SetMaxOpenConns(maxOpen)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+	return db
+}
+
+func TestDBPoolCollector(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	c := newDBPoolCollector(&metricConfig{MetricPrefix: "test"})
+	first := newDBForTest(t, 5)
+	second := newDBForTest(t, 3)
+	unlimited := newDBForTest(t, 0)
+
+	// Pools of two connectors connected with the same name are summed up
+	c.add("master", first)
+	c.add("master", second)
+	c.add("report", unlimited)
+	r.NoError(testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP test_db_max_open_connections Monitor the maximum number of open connections of a database pool, 0 is unlimited
+# TYPE test_db_max_open_connections gauge
+test_db_max_open_connections{db="master"} 8
+test_db_max_open_connections{db="report"} 0
+`), "test_db_max_open_connections"))
+
+	c.add("report", newDBForTest(t, 2))
+	c.remove(second)
+	r.NoError(testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP test_db_max_open_connections Monitor the maximum number of open connections of a database pool, 0 is unlimited
+# TYPE test_db_max_open_connections gauge
+test_db_max_open_connections{db="master"} 5
+test_db_max_open_connections{db="report"} 0
+`), "test_db_max_open_connections"))
+
+	c.remove(first)
+	c.remove(unlimited)
+	// Only the second pool of report is left
+	r.Equal(9, testutil.CollectAndCount(c))
+}
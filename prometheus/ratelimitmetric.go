@@ -0,0 +1,56 @@
+package prometheus
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/saigontechnology/go-shared-packages/must"
+)
+
+const (
+	vectorLimiter                = "limiter"
+	nameRateLimitRejectedTotal   = "rate_limit_rejected_total"
+	descriptionRateLimitRejected = "Monitor requests rejected by rate limiters"
+)
+
+var (
+	rateLimitMetricOnce     sync.Once
+	rateLimitMetricInstance *rateLimitMetric
+)
+
+type RateLimitMetric interface {
+	CountRejected(limiter string)
+}
+
+type rateLimitMetric struct {
+	cfg           *rateLimitMetricConfig
+	rejectedTotal *prometheus.CounterVec
+}
+
+func GetRateLimitMetric() RateLimitMetric {
+	rateLimitMetricOnce.Do(func() {
+		cfg, err := newRateLimitMetricConfig()
+		must.NotFail(err)
+		rejectedTotal := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Metric.Namespace,
+			Name:      fmt.Sprintf("%s_%s", cfg.Metric.MetricPrefix, nameRateLimitRejectedTotal),
+			Help:      descriptionRateLimitRejected,
+		}, []string{vectorLimiter})
+		prometheus.MustRegister(rejectedTotal)
+		rateLimitMetricInstance = &rateLimitMetric{
+			cfg:           cfg,
+			rejectedTotal: rejectedTotal,
+		}
+	})
+
+	return rateLimitMetricInstance
+}
+
+func (m *rateLimitMetric) CountRejected(limiter string) {
+	if !m.cfg.RateLimitMetricEnabled {
+		return
+	}
+	m.rejectedTotal.WithLabelValues(limiter).Inc()
+}
@@ -0,0 +1,36 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type Config struct {
+	Namespace string `default:"your_service" envconfig:"QUEUE_NAMESPACE"`
+	// Concurrency is the number of jobs a consumer handles at the same time
+	Concurrency int `default:"10" envconfig:"QUEUE_CONCURRENCY"`
+	// A job is moved to the dead-letter stream after MaxAttempts failed attempts
+	MaxAttempts int `default:"5" envconfig:"QUEUE_MAX_ATTEMPTS"`
+	// RetryBackoff is doubled after each failed attempt, up to MaxRetryBackoff
+	RetryBackoff      time.Duration `default:"1s"  envconfig:"QUEUE_RETRY_BACKOFF"`
+	MaxRetryBackoff   time.Duration `default:"5m"  envconfig:"QUEUE_MAX_RETRY_BACKOFF"`
+	RetryPollInterval time.Duration `default:"1s"  envconfig:"QUEUE_RETRY_POLL_INTERVAL"`
+	// Jobs read by a consumer which did not ack them for ClaimMinIdle are reclaimed every ClaimInterval,
+	// ClaimMinIdle must be longer than the longest job
+	ClaimInterval time.Duration `default:"30s" envconfig:"QUEUE_CLAIM_INTERVAL"`
+	ClaimMinIdle  time.Duration `default:"5m"  envconfig:"QUEUE_CLAIM_MIN_IDLE"`
+	// BlockTimeout bounds how long a consumer waits for new jobs, it also bounds how long it takes to stop
+	BlockTimeout time.Duration `default:"5s" envconfig:"QUEUE_BLOCK_TIMEOUT"`
+}
+
+// NewConfig reads the default queue configuration from environment variables.
+func NewConfig() (*Config, error) {
+	cfg := &Config{}
+	err := envconfig.Process("", cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
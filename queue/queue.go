@@ -0,0 +1,390 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/saigontechnology/go-shared-packages/cache"
+)
+
+const (
+	fieldID      = "id"
+	fieldAttempt = "attempt"
+	fieldPayload = "payload"
+	fieldError   = "error"
+)
+
+// retryScript moves the jobs whose backoff is over from the retry set back to the stream.
+// Members of the retry set are "<id>:<attempt>", their payload is kept in a hash.
+var retryScript = redis.NewScript(`
+local members = redis.call("ZRANGEBYSCORE", KEYS[2], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+for _, member in ipairs(members) do
+	local separator = string.find(member, ":", 1, true)
+	local payload = redis.call("HGET", KEYS[3], member)
+	if payload then
+		redis.call(
+			"XADD", KEYS[1], "*",
+			"id", string.sub(member, 1, separator - 1),
+			"attempt", string.sub(member, separator + 1),
+			"payload", payload
+		)
+	end
+	redis.call("ZREM", KEYS[2], member)
+	redis.call("HDEL", KEYS[3], member)
+end
+return #members
+`)
+
+// Job is a message of a queue. ID is kept across attempts, Attempt starts at 1.
+// An attempt also counts when the consumer handling the job stopped before acking it.
+type Job[T any] struct {
+	ID      string
+	Attempt int
+	Payload T
+}
+
+// Handler processes a job, the job is retried when an error is returned.
+type Handler[T any] func(ctx context.Context, job *Job[T]) error
+
+// delivery is a stream entry with the number of times it was delivered to consumers of the group.
+type delivery struct {
+	message redis.XMessage
+	count   int64
+}
+
+// Queue is a durable work queue on a Redis stream with one consumer group.
+// Acked jobs are deleted from the stream, failed jobs are retried with exponential backoff
+// and moved to the dead-letter stream after Config.MaxAttempts attempts.
+type Queue[T any] struct {
+	client redis.UniversalClient
+	codec  cache.Codec
+	cfg    *Config
+	name   string
+	// All keys share the hash tag of the queue, so scripts and transactions work in cluster mode
+	stream     string
+	retrySet   string
+	retryData  string
+	deadLetter string
+}
+
+// New creates a Queue, cache.GetProvider().RedisClient() can be used as client. JSONCodec is used when codec is nil.
+func New[T any](client redis.UniversalClient, name string, codec cache.Codec, cfg *Config) (*Queue[T], error) {
+	if cfg.Concurrency <= 0 || cfg.MaxAttempts <= 0 {
+		return nil, fmt.Errorf(
+			"[Queue] concurrency and max attempts must be positive, got %d and %d",
+			cfg.Concurrency,
+			cfg.MaxAttempts,
+		)
+	}
+	if codec == nil {
+		codec = cache.JSONCodec{}
+	}
+	stream := fmt.Sprintf("%s_queue:{%s}", cfg.Namespace, name)
+	return &Queue[T]{
+		client:     client,
+		codec:      codec,
+		cfg:        cfg,
+		name:       name,
+		stream:     stream,
+		retrySet:   stream + ":retry",
+		retryData:  stream + ":retry_payload",
+		deadLetter: stream + ":dead",
+	}, nil
+}
+
+// DeadLetterStream is the key of the stream holding jobs which failed Config.MaxAttempts times.
+// Its entries have the id, attempt, payload and error fields.
+func (q *Queue[T]) DeadLetterStream() string {
+	return q.deadLetter
+}
+
+// Enqueue adds a job to the queue and returns its ID.
+func (q *Queue[T]) Enqueue(ctx context.Context, payload T) (string, error) {
+	s := q.newrelicRedisSegment(ctx, "Enqueue")
+	defer s.End()
+	raw, err := q.codec.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	id := uuid.NewString()
+	err = q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.stream,
+		Values: []interface{}{fieldID, id, fieldAttempt, 1, fieldPayload, raw},
+	}).Err()
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Consume handles jobs with Config.Concurrency workers until ctx is done, then it waits for the jobs
+// being handled. Handlers get a context which is not cancelled with ctx, so they can finish their job.
+func (q *Queue[T]) Consume(ctx context.Context, handler Handler[T]) error {
+	err := q.client.XGroupCreateMkStream(ctx, q.stream, q.name, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	hostname, _ := os.Hostname()
+	consumer := fmt.Sprintf("%s-%s", hostname, uuid.NewString())
+	messages := make(chan delivery)
+	var wg sync.WaitGroup
+	for i := 0; i < q.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range messages {
+				q.handle(context.WithoutCancel(ctx), d, handler)
+			}
+		}()
+	}
+
+	var loops sync.WaitGroup
+	loops.Add(2)
+	go func() {
+		defer loops.Done()
+		q.retryLoop(ctx)
+	}()
+	go func() {
+		defer loops.Done()
+		q.claimLoop(ctx, consumer, messages)
+	}()
+	q.readLoop(ctx, consumer, messages)
+	loops.Wait()
+	close(messages)
+	wg.Wait()
+	return nil
+}
+
+func (q *Queue[T]) readLoop(ctx context.Context, consumer string, messages chan<- delivery) {
+	for ctx.Err() == nil {
+		streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    q.name,
+			Consumer: consumer,
+			Streams:  []string{q.stream, ">"},
+			Count:    int64(q.cfg.Concurrency),
+			Block:    q.cfg.BlockTimeout,
+		}).Result()
+		if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+			continue
+		}
+		if err != nil {
+			log.Printf("[Queue] could not read jobs of %s. Error: %s", q.name, err.Error())
+			q.sleep(ctx, q.cfg.RetryPollInterval)
+			continue
+		}
+		for _, stream := range streams {
+			// New entries are delivered for the first time
+			for _, message := range stream.Messages {
+				messages <- delivery{message: message, count: 1}
+			}
+		}
+	}
+}
+
+// claimLoop takes over jobs of consumers which crashed before acking them.
+func (q *Queue[T]) claimLoop(ctx context.Context, consumer string, messages chan<- delivery) {
+	for q.sleep(ctx, q.cfg.ClaimInterval) {
+		start := "0-0"
+		for {
+			claimed, next, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+				Stream:   q.stream,
+				Group:    q.name,
+				Consumer: consumer,
+				MinIdle:  q.cfg.ClaimMinIdle,
+				Start:    start,
+				Count:    int64(q.cfg.Concurrency),
+			}).Result()
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("[Queue] could not claim jobs of %s. Error: %s", q.name, err.Error())
+				}
+				break
+			}
+			counts, err := q.deliveryCounts(ctx, claimed)
+			if err != nil {
+				// The jobs are claimed again after ClaimMinIdle
+				if ctx.Err() == nil {
+					log.Printf("[Queue] could not read delivery counts of %s. Error: %s", q.name, err.Error())
+				}
+				break
+			}
+			for i, message := range claimed {
+				messages <- delivery{message: message, count: counts[i]}
+			}
+			if next == "0-0" || next == "" {
+				break
+			}
+			start = next
+		}
+	}
+}
+
+// deliveryCounts reads from the pending entries list how many times each message was delivered,
+// claiming a message counts as a delivery.
+func (q *Queue[T]) deliveryCounts(ctx context.Context, messages []redis.XMessage) ([]int64, error) {
+	cmds := make([]*redis.XPendingExtCmd, len(messages))
+	_, err := q.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, message := range messages {
+			cmds[i] = pipe.XPendingExt(ctx, &redis.XPendingExtArgs{
+				Stream: q.stream,
+				Group:  q.name,
+				Start:  message.ID,
+				End:    message.ID,
+				Count:  1,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]int64, len(messages))
+	for i, cmd := range cmds {
+		pending := cmd.Val()
+		if len(pending) == 0 {
+			return nil, fmt.Errorf("[Queue] message %s is not pending", messages[i].ID)
+		}
+		counts[i] = pending[0].RetryCount
+	}
+	return counts, nil
+}
+
+func (q *Queue[T]) retryLoop(ctx context.Context) {
+	for q.sleep(ctx, q.cfg.RetryPollInterval) {
+		err := retryScript.Run(
+			ctx,
+			q.client,
+			[]string{q.stream, q.retrySet, q.retryData},
+			time.Now().UnixMilli(),
+			q.cfg.Concurrency,
+		).Err()
+		if err != nil && ctx.Err() == nil {
+			log.Printf("[Queue] could not retry jobs of %s. Error: %s", q.name, err.Error())
+		}
+	}
+}
+
+func (q *Queue[T]) handle(ctx context.Context, d delivery, handler Handler[T]) {
+	message := d.message
+	id, _ := message.Values[fieldID].(string)
+	attempt, _ := strconv.Atoi(fmt.Sprint(message.Values[fieldAttempt]))
+	// Each earlier delivery of the entry was an attempt whose consumer stopped before acking it
+	attempt += int(d.count) - 1
+	raw, _ := message.Values[fieldPayload].(string)
+	if attempt > q.cfg.MaxAttempts {
+		// The job keeps stopping its consumers, it is not run again
+		job := &Job[T]{ID: id, Attempt: attempt - 1}
+		q.fail(ctx, message, job, raw, errors.New("[Queue] consumer stopped while handling the job"), true)
+		return
+	}
+	job := &Job[T]{ID: id, Attempt: attempt}
+	if err := q.codec.Unmarshal([]byte(raw), &job.Payload); err != nil {
+		// The payload will never be decoded, retrying it is pointless
+		q.fail(ctx, message, job, raw, fmt.Errorf("[Queue] could not decode job: %w", err), true)
+		return
+	}
+
+	if err := q.run(ctx, job, handler); err != nil {
+		q.fail(ctx, message, job, raw, err, attempt >= q.cfg.MaxAttempts)
+		return
+	}
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAck(ctx, q.stream, q.name, message.ID)
+		pipe.XDel(ctx, q.stream, message.ID)
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Queue] could not ack job %s of %s. Error: %s", id, q.name, err.Error())
+	}
+}
+
+func (q *Queue[T]) run(ctx context.Context, job *Job[T], handler Handler[T]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("[Queue] job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// fail schedules the job for a retry or moves it to the dead-letter stream, the stream entry is acked either way.
+func (q *Queue[T]) fail(
+	ctx context.Context,
+	message redis.XMessage,
+	job *Job[T],
+	raw string,
+	jobErr error,
+	dead bool,
+) {
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if dead {
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: q.deadLetter,
+				Values: []interface{}{
+					fieldID, job.ID,
+					fieldAttempt, job.Attempt,
+					fieldPayload, raw,
+					fieldError, jobErr.Error(),
+				},
+			})
+		} else {
+			member := fmt.Sprintf("%s:%d", job.ID, job.Attempt+1)
+			dueAt := time.Now().Add(q.backoff(job.Attempt))
+			pipe.HSet(ctx, q.retryData, member, raw)
+			pipe.ZAdd(ctx, q.retrySet, redis.Z{Score: float64(dueAt.UnixMilli()), Member: member})
+		}
+		pipe.XAck(ctx, q.stream, q.name, message.ID)
+		pipe.XDel(ctx, q.stream, message.ID)
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Queue] could not reschedule job %s of %s. Error: %s", job.ID, q.name, err.Error())
+		return
+	}
+	if dead {
+		log.Printf("[Queue] job %s of %s failed %d times. Error: %s", job.ID, q.name, job.Attempt, jobErr.Error())
+	}
+}
+
+func (q *Queue[T]) backoff(attempt int) time.Duration {
+	backoff := q.cfg.RetryBackoff
+	for i := 1; i < attempt && backoff < q.cfg.MaxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if q.cfg.MaxRetryBackoff > 0 && backoff > q.cfg.MaxRetryBackoff {
+		return q.cfg.MaxRetryBackoff
+	}
+	return backoff
+}
+
+// sleep reports whether d passed before ctx was done.
+func (q *Queue[T]) sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (q *Queue[T]) newrelicRedisSegment(ctx context.Context, operation string) *newrelic.DatastoreSegment {
+	return &newrelic.DatastoreSegment{
+		StartTime: newrelic.FromContext(ctx).StartSegmentNow(),
+		Product:   newrelic.DatastoreRedis,
+		Operation: operation,
+	}
+}
@@ -0,0 +1,196 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/saigontechnology/go-shared-packages/queue"
+)
+
+type jobForTest struct {
+	Name string
+}
+
+func newConfigForTest() *queue.Config {
+	return &queue.Config{
+		Namespace:         "test",
+		Concurrency:       2,
+		MaxAttempts:       3,
+		RetryBackoff:      10 * time.Millisecond,
+		MaxRetryBackoff:   time.Second,
+		RetryPollInterval: 10 * time.Millisecond,
+		ClaimInterval:     10 * time.Millisecond,
+		ClaimMinIdle:      50 * time.Millisecond,
+		BlockTimeout:      50 * time.Millisecond,
+	}
+}
+
+// consumeForTest consumes q until the test ends.
+func consumeForTest[T any](t *testing.T, q *queue.Queue[T], handler queue.Handler[T]) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, q.Consume(ctx, handler))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestQueue_Retry(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	q, err := queue.New[jobForTest](client, "emails", nil, newConfigForTest())
+	r.NoError(err)
+
+	var mu sync.Mutex
+	attempts := make(map[string][]int)
+	consumeForTest(t, q, func(ctx context.Context, job *queue.Job[jobForTest]) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[job.Payload.Name] = append(attempts[job.Payload.Name], job.Attempt)
+		switch job.Payload.Name {
+		case "flaky":
+			if job.Attempt < 2 {
+				return errors.New("flaky")
+			}
+		case "broken":
+			panic("broken")
+		}
+		return nil
+	})
+
+	for _, name := range []string{"ok", "flaky", "broken"} {
+		_, err := q.Enqueue(ctx, jobForTest{Name: name})
+		r.NoError(err)
+	}
+
+	r.Eventually(func() bool {
+		entries, err := client.XRange(ctx, q.DeadLetterStream(), "-", "+").Result()
+		return err == nil && len(entries) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	r.Equal([]int{1}, attempts["ok"])
+	r.Equal([]int{1, 2}, attempts["flaky"])
+	r.Equal([]int{1, 2, 3}, attempts["broken"])
+	mu.Unlock()
+
+	entries, err := client.XRange(ctx, q.DeadLetterStream(), "-", "+").Result()
+	r.NoError(err)
+	r.Equal("3", entries[0].Values["attempt"])
+	r.Contains(entries[0].Values["error"], "broken")
+}
+
+func TestQueue_Reclaim(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	q, err := queue.New[jobForTest](client, "reports", nil, newConfigForTest())
+	r.NoError(err)
+
+	// A consumer reads the job and crashes before acking it
+	stream := "test_queue:{reports}"
+	r.NoError(client.XGroupCreateMkStream(ctx, stream, "reports", "0").Err())
+	_, err = q.Enqueue(ctx, jobForTest{Name: "report"})
+	r.NoError(err)
+	r.NoError(client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    "reports",
+		Consumer: "crashed",
+		Streams:  []string{stream, ">"},
+		Count:    1,
+	}).Err())
+
+	handled := make(chan string, 1)
+	consumeForTest(t, q, func(ctx context.Context, job *queue.Job[jobForTest]) error {
+		handled <- job.Payload.Name
+		return nil
+	})
+
+	select {
+	case name := <-handled:
+		r.Equal("report", name)
+	case <-time.After(5 * time.Second):
+		r.Fail("the job was not reclaimed")
+	}
+	r.Eventually(func() bool {
+		n, err := client.XLen(ctx, stream).Result()
+		return err == nil && n == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestQueue_ReclaimCrashLoop(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	cfg := newConfigForTest()
+	q, err := queue.New[jobForTest](client, "imports", nil, cfg)
+	r.NoError(err)
+
+	// Every consumer handling the job crashes before acking it, each delivery is an attempt
+	stream := "test_queue:{imports}"
+	r.NoError(client.XGroupCreateMkStream(ctx, stream, "imports", "0").Err())
+	_, err = q.Enqueue(ctx, jobForTest{Name: "import"})
+	r.NoError(err)
+	r.NoError(client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    "imports",
+		Consumer: "crashed",
+		Streams:  []string{stream, ">"},
+		Count:    1,
+	}).Err())
+	for i := 1; i < cfg.MaxAttempts; i++ {
+		r.NoError(client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   stream,
+			Group:    "imports",
+			Consumer: "crashed",
+			Start:    "0-0",
+			Count:    1,
+		}).Err())
+	}
+
+	var handled atomic.Int32
+	consumeForTest(t, q, func(ctx context.Context, job *queue.Job[jobForTest]) error {
+		handled.Add(1)
+		return nil
+	})
+
+	r.Eventually(func() bool {
+		entries, err := client.XRange(ctx, q.DeadLetterStream(), "-", "+").Result()
+		return err == nil && len(entries) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	entries, err := client.XRange(ctx, q.DeadLetterStream(), "-", "+").Result()
+	r.NoError(err)
+	r.Equal("3", entries[0].Values["attempt"])
+	r.Contains(entries[0].Values["error"], "consumer stopped")
+	r.Zero(handled.Load())
+	n, err := client.XLen(ctx, stream).Result()
+	r.NoError(err)
+	r.Zero(n)
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	t.Parallel()
+	cfg := newConfigForTest()
+	cfg.Concurrency = 0
+	_, err := queue.New[jobForTest](nil, "jobs", nil, cfg)
+	require.Error(t, err)
+}
@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type Algorithm string
+
+const (
+	// AlgorithmGCRA smooths requests over the period and allows bursts up to Burst requests.
+	AlgorithmGCRA Algorithm = "gcra"
+	// AlgorithmSlidingWindow allows at most Limit requests in any window of Period.
+	AlgorithmSlidingWindow Algorithm = "sliding_window"
+)
+
+type Config struct {
+	Algorithm Algorithm     `default:"gcra"         envconfig:"RATE_LIMIT_ALGORITHM"`
+	Limit     int           `default:"100"          envconfig:"RATE_LIMIT_LIMIT"`
+	Period    time.Duration `default:"1m"           envconfig:"RATE_LIMIT_PERIOD"`
+	// Burst is only used by GCRA, 0 means Limit
+	Burst     int    `default:"0"            envconfig:"RATE_LIMIT_BURST"`
+	Namespace string `default:"your_service" envconfig:"RATE_LIMIT_NAMESPACE"`
+}
+
+// NewConfig reads the default rate limit configuration from environment variables.
+func NewConfig() (*Config, error) {
+	cfg := &Config{}
+	err := envconfig.Process("", cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
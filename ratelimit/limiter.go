@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/redis/go-redis/v9"
+)
+
+// gcraScript implements the generic cell rate algorithm, the key holds the theoretical arrival time.
+// All times are in milliseconds.
+var gcraScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local emission = tonumber(ARGV[2])
+local burst_offset = tonumber(ARGV[3])
+local tat = tonumber(redis.call("GET", KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+local new_tat = tat + emission
+local diff = now - (new_tat - burst_offset)
+if diff < 0 then
+	return {0, 0, math.ceil(-diff), math.ceil(tat - now)}
+end
+redis.call("SET", KEYS[1], new_tat, "PX", math.ceil(new_tat - now))
+return {1, math.floor(diff / emission), 0, math.ceil(new_tat - now)}
+`)
+
+// slidingWindowScript keeps the time of each accepted request in a sorted set.
+// All times are in milliseconds.
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - period)
+local count = redis.call("ZCARD", KEYS[1])
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+local reset_after = 0
+if #oldest > 0 then
+	reset_after = tonumber(oldest[2]) + period - now
+end
+if count >= limit then
+	return {0, 0, reset_after, reset_after}
+end
+redis.call("ZADD", KEYS[1], now, ARGV[4])
+redis.call("PEXPIRE", KEYS[1], period)
+if count == 0 then
+	reset_after = period
+end
+return {1, limit - count - 1, 0, reset_after}
+`)
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is the time to wait before the next request is allowed, it is 0 when the request is allowed.
+	RetryAfter time.Duration
+	// ResetAfter is the time until the limiter is back to its full capacity.
+	ResetAfter time.Duration
+}
+
+type Limiter interface {
+	// Allow consumes one request of key.
+	Allow(ctx context.Context, key string) (*Result, error)
+}
+
+type redisLimiter struct {
+	client redis.UniversalClient
+	cfg    *Config
+	now    func() time.Time
+}
+
+// NewLimiter creates a Redis-backed Limiter, cache.GetProvider().RedisClient() can be used as client.
+// Times are taken from the clock of the caller, so instances of a service must have synchronized clocks.
+func NewLimiter(client redis.UniversalClient, cfg *Config) (Limiter, error) {
+	if cfg.Limit <= 0 || cfg.Period <= 0 {
+		return nil, fmt.Errorf("[RateLimit] limit and period must be positive, got %d per %s", cfg.Limit, cfg.Period)
+	}
+	if cfg.Algorithm != AlgorithmGCRA && cfg.Algorithm != AlgorithmSlidingWindow {
+		return nil, fmt.Errorf("[RateLimit] algorithm %s is not supported", cfg.Algorithm)
+	}
+	return &redisLimiter{
+		client: client,
+		cfg:    cfg,
+		now:    time.Now,
+	}, nil
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string) (*Result, error) {
+	s := &newrelic.DatastoreSegment{
+		StartTime: newrelic.FromContext(ctx).StartSegmentNow(),
+		Product:   newrelic.DatastoreRedis,
+		Operation: "RateLimit",
+	}
+	defer s.End()
+
+	limitKey := fmt.Sprintf("%s_rate_limit:%s", l.cfg.Namespace, key)
+	now := l.now().UnixMilli()
+	var cmd *redis.Cmd
+	switch l.cfg.Algorithm {
+	case AlgorithmSlidingWindow:
+		cmd = slidingWindowScript.Run(
+			ctx,
+			l.client,
+			[]string{limitKey},
+			now,
+			l.cfg.Period.Milliseconds(),
+			l.cfg.Limit,
+			uuid.NewString(),
+		)
+	default:
+		burst := l.cfg.Burst
+		if burst <= 0 {
+			burst = l.cfg.Limit
+		}
+		emission := float64(l.cfg.Period.Milliseconds()) / float64(l.cfg.Limit)
+		cmd = gcraScript.Run(ctx, l.client, []string{limitKey}, now, emission, emission*float64(burst))
+	}
+
+	values, err := cmd.Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Allowed:    values[0] == 1,
+		Limit:      l.cfg.Limit,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		ResetAfter: time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
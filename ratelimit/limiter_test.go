@@ -0,0 +1,111 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/saigontechnology/go-shared-packages/ratelimit"
+)
+
+func newLimiterForTest(t *testing.T, cfg *ratelimit.Config) ratelimit.Limiter {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	limiter, err := ratelimit.NewLimiter(redis.NewClient(&redis.Options{Addr: mr.Addr()}), cfg)
+	require.NoError(t, err)
+	return limiter
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name               string
+		cfg                *ratelimit.Config
+		expectedRemaining  []int
+		expectedRetryAfter time.Duration
+	}{
+		{
+			name: "GCRA",
+			cfg: &ratelimit.Config{
+				Algorithm: ratelimit.AlgorithmGCRA,
+				Limit:     3,
+				Period:    time.Minute,
+				Namespace: "test",
+			},
+			expectedRemaining:  []int{2, 1, 0},
+			expectedRetryAfter: 20 * time.Second,
+		},
+		{
+			name: "Sliding window",
+			cfg: &ratelimit.Config{
+				Algorithm: ratelimit.AlgorithmSlidingWindow,
+				Limit:     3,
+				Period:    time.Minute,
+				Namespace: "test",
+			},
+			expectedRemaining:  []int{2, 1, 0},
+			expectedRetryAfter: time.Minute,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+			ctx := context.Background()
+			limiter := newLimiterForTest(t, tc.cfg)
+			for _, remaining := range tc.expectedRemaining {
+				result, err := limiter.Allow(ctx, "key")
+				r.NoError(err)
+				r.True(result.Allowed)
+				r.Equal(remaining, result.Remaining)
+			}
+
+			result, err := limiter.Allow(ctx, "key")
+			r.NoError(err)
+			r.False(result.Allowed)
+			r.InDelta(tc.expectedRetryAfter.Seconds(), result.RetryAfter.Seconds(), 1)
+
+			// Keys are limited separately
+			result, err = limiter.Allow(ctx, "another-key")
+			r.NoError(err)
+			r.True(result.Allowed)
+		})
+	}
+}
+
+func TestGinRateLimitMiddleware(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+	limiter := newLimiterForTest(t, &ratelimit.Config{
+		Algorithm: ratelimit.AlgorithmSlidingWindow,
+		Limit:     1,
+		Period:    time.Minute,
+		Namespace: "test",
+	})
+	engine := gin.New()
+	engine.GET("/ping", ratelimit.GinRateLimitMiddleware("ping", limiter, ratelimit.ByClientIP), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	r.Equal(http.StatusOK, rec.Code)
+	r.Equal("1", rec.Header().Get(ratelimit.HeaderRateLimitLimit))
+	r.Equal("0", rec.Header().Get(ratelimit.HeaderRateLimitRemaining))
+	r.Equal("60", rec.Header().Get(ratelimit.HeaderRateLimitReset))
+
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	r.Equal(http.StatusTooManyRequests, rec.Code)
+	r.Equal("60", rec.Header().Get(ratelimit.HeaderRetryAfter))
+}
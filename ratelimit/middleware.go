@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	authmiddleware "github.com/saigontechnology/go-shared-packages/auth-middleware"
+	"github.com/saigontechnology/go-shared-packages/logger"
+	"github.com/saigontechnology/go-shared-packages/prometheus"
+)
+
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRetryAfter         = "Retry-After"
+)
+
+// KeyFunc returns the key a request is counted by, requests with an empty key are not limited.
+type KeyFunc func(c *gin.Context) string
+
+func ByClientIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// ByAccountID requires authmiddleware.AuthInjectionMiddleware to run before the rate limit middleware.
+func ByAccountID(c *gin.Context) string {
+	return c.GetString(authmiddleware.AccountIDKey)
+}
+
+func ByRoute(c *gin.Context) string {
+	return fmt.Sprintf("%s %s", c.Request.Method, c.FullPath())
+}
+
+// GinRateLimitMiddleware rejects requests with 429 Too Many Requests once the limit of their key is reached.
+// The name separates counters of different limiters and labels the rejected requests metric.
+// Requests are allowed when the limiter fails, so a Redis outage does not take the API down.
+func GinRateLimitMiddleware(name string, limiter Limiter, keyFunc KeyFunc) gin.HandlerFunc {
+	metric := prometheus.GetRateLimitMetric()
+	return func(c *gin.Context) {
+		key := keyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		result, err := limiter.Allow(c, fmt.Sprintf("%s:%s", name, key))
+		if err != nil {
+			logger.GetProvider().Logger().Error(c, fmt.Sprintf("[RateLimit] could not check limit of %s: %s", name, err))
+			c.Next()
+			return
+		}
+
+		c.Header(HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+		c.Header(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+		c.Header(HeaderRateLimitReset, formatSeconds(result.ResetAfter))
+		if !result.Allowed {
+			metric.CountRejected(name)
+			c.Header(HeaderRetryAfter, formatSeconds(result.RetryAfter))
+			c.AbortWithStatus(http.StatusTooManyRequests)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// formatSeconds rounds up, so clients never retry too early.
+func formatSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -19,6 +19,7 @@ import (
 
 const (
 	TypeItOptionDatabaseInitDir = "ItOptionDatabaseInitDir"
+	TypeItOptionFakeCache       = "ItOptionFakeCache"
 )
 
 // IT is a utility to implement integration test.
@@ -27,6 +28,8 @@ type IT interface {
 	DB() *gorm.DB
 	FixtureStore() fixture.Store
 	Cache() cache.Cache
+	// FakeCache is the cache of the test when ItOptionFakeCache is given, it is nil otherwise.
+	FakeCache() *cache.FakeCache
 }
 
 // it is an Integration Test utility with Sqlite database.
@@ -37,6 +40,7 @@ type it struct {
 	db           *gorm.DB
 	fixtureStore fixture.Store
 	cache        cache.Cache
+	fakeCache    *cache.FakeCache
 }
 
 type ItOption interface {
@@ -56,6 +60,17 @@ func (o *ItOptionDatabaseInitDir) Value() string {
 	return o.InitDir
 }
 
+// ItOptionFakeCache replaces the miniredis cache with a cache.FakeCache, which records calls.
+type ItOptionFakeCache struct{}
+
+func (o *ItOptionFakeCache) Type() string {
+	return TypeItOptionFakeCache
+}
+
+func (o *ItOptionFakeCache) Value() string {
+	return ""
+}
+
 func NewIT(t *testing.T, options ...ItOption) IT {
 	t.Helper()
 
@@ -72,7 +87,12 @@ func newIT(t *testing.T, options ...ItOption) *it {
 	must.NotFail(err)
 	parseItOptions(cfg, options...)
 	it.cfg = cfg
-	it.cache = cache.NewMiniRedisForTest(t)
+	if cfg.FakeCache {
+		it.fakeCache = cache.NewFakeCache()
+		it.cache = it.fakeCache
+	} else {
+		it.cache = cache.NewMiniRedisForTest(t)
+	}
 	it.fixtureStore = fixture.NewStore()
 	it.createSqliteDB()
 	it.initDatabase()
@@ -83,8 +103,11 @@ func newIT(t *testing.T, options ...ItOption) *it {
 
 func parseItOptions(cfg *itConfig, options ...ItOption) {
 	for _, option := range options {
-		if option.Type() == TypeItOptionDatabaseInitDir {
+		switch option.Type() {
+		case TypeItOptionDatabaseInitDir:
 			cfg.SqliteTestDatabaseInitDir = option.Value()
+		case TypeItOptionFakeCache:
+			cfg.FakeCache = true
 		}
 	}
 }
@@ -101,6 +124,10 @@ func (i *it) Cache() cache.Cache {
 	return i.cache
 }
 
+func (i *it) FakeCache() *cache.FakeCache {
+	return i.fakeCache
+}
+
 func (i *it) createSqliteDB() {
 	i.generateDBFile()
 	err := os.MkdirAll(filepath.Dir(i.dbFile), 0o770)
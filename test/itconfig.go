@@ -7,6 +7,8 @@ type itConfig struct {
 	SqliteTestDatabaseInitDir         string `default:"../../test/sqlite" envconfig:"SQLITE_TEST_DATABASE_INIT_DIR"`
 	SqliteTestDatabaseSchemaFile      string `default:"schema.sql"        envconfig:"SQLITE_TEST_DATABASE_SCHEMA_FILE"`
 	SqliteTestDatabaseInitialDataFile string `default:"initial_data.sql"  envconfig:"SQLITE_TEST_DATABASE_INITIAL_DATA_FILE"`
+	// FakeCache is set by ItOptionFakeCache
+	FakeCache bool `ignored:"true"`
 }
 
 func newItConfig() (*itConfig, error) {